@@ -1,22 +1,36 @@
 package main
 
 import (
+	"context"
+	stdErrors "errors"
 	"flag"
 	"fmt"
 	"iwut-smartclass-backend/assets"
 	"iwut-smartclass-backend/internal/application/course"
+	"iwut-smartclass-backend/internal/application/prompt"
+	"iwut-smartclass-backend/internal/application/summary"
+	"iwut-smartclass-backend/internal/application/usage"
 	"iwut-smartclass-backend/internal/database"
 	"iwut-smartclass-backend/internal/infrastructure/config"
 	"iwut-smartclass-backend/internal/infrastructure/external"
 	"iwut-smartclass-backend/internal/infrastructure/logger"
 	"iwut-smartclass-backend/internal/infrastructure/persistence"
+	"iwut-smartclass-backend/internal/infrastructure/ratelimit"
 	"iwut-smartclass-backend/internal/interfaces/http"
 	httpHandlers "iwut-smartclass-backend/internal/interfaces/http/handlers"
 	httpMiddleware "iwut-smartclass-backend/internal/interfaces/http/middleware"
 	"iwut-smartclass-backend/internal/middleware"
+	netHttp "net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 )
 
+// HTTP 服务关闭时等待请求处理完成的最长时间
+const httpShutdownTimeout = 10 * time.Second
+
 func main() {
 	fmt.Println(`
 	  _____                      _     _____ _
@@ -46,6 +60,11 @@ func main() {
 		panic(fmt.Sprintf("Failed to initialize logger: %v", err))
 	}
 
+	// 未配置令牌密钥时令牌不会持久化，仍需令牌的任务重启后无法恢复
+	if cfg.JobTokenKey == "" {
+		appLogger.Warn("JOB_TOKEN_KEY is not set, unfinished jobs that still need the user token cannot resume after a restart")
+	}
+
 	// 列出所有嵌入的静态资源
 	assetsList, err := assets.ListAssets()
 	if err != nil {
@@ -77,40 +96,60 @@ func main() {
 	// 初始化仓储
 	courseRepo := persistence.NewCourseRepository(db, appLogger)
 	summaryRepo := persistence.NewSummaryRepository(db, appLogger)
+	promptRepo := persistence.NewPromptRepository(db, appLogger)
+	transcriptRepo := persistence.NewTranscriptRepository(db, appLogger)
+	usageRepo := persistence.NewUsageRepository(db, appLogger)
 
 	// 初始化外部服务
-	userService := external.NewUserService(cfg, appLogger)
+	userService := external.SharedUserService(cfg, appLogger)
 	scheduleService := external.NewScheduleService(cfg, appLogger)
 	liveCourseService := external.NewLiveCourseService(cfg, appLogger)
 	videoAuthService := external.NewVideoAuthService(cfg, appLogger)
+	videoSigner := external.NewVideoURLSignerFromConfig(cfg)
 	ffmpegService := external.NewFFmpegService(appLogger)
-	cosService, err := external.NewCOSService(cfg.TencentSecretId[0], cfg.TencentSecretKey[0], cfg.BucketUrl, appLogger)
+	credentialPool, err := external.SharedCredentialPool(cfg, appLogger)
+	if err != nil {
+		appLogger.Error("Failed to initialize Tencent credentials", logger.String("error", err.Error()))
+		return
+	}
+	cosService, err := external.NewCOSServiceFromConfig(cfg, appLogger)
 	if err != nil {
 		appLogger.Error("Failed to initialize COS service", logger.String("error", err.Error()))
 		return
 	}
-	asrService, err := external.NewASRService(cfg.TencentSecretId[0], cfg.TencentSecretKey[0], appLogger)
+	asrEngine, err := external.NewASREngine(cfg, appLogger)
 	if err != nil {
-		appLogger.Error("Failed to initialize ASR service", logger.String("error", err.Error()))
+		appLogger.Error("Failed to initialize ASR engine", logger.String("error", err.Error()))
+		return
+	}
+	llmProvider := external.NewLLMProvider(cfg, appLogger)
+	summaryLimiter, err := ratelimit.NewLimiterFromConfig(cfg, appLogger)
+	if err != nil {
+		appLogger.Error("Failed to initialize rate limiter", logger.String("error", err.Error()))
 		return
 	}
-	openaiService := external.NewOpenAIService(cfg, appLogger)
 
 	// 初始化应用服务
 	courseService := course.NewService(courseRepo, appLogger)
+	promptRegistry := prompt.NewRegistry(promptRepo, appLogger)
+	usageService := usage.NewService(usageRepo, appLogger)
 
 	// 初始化工作队列
-	middleware.InitQueues(cfg, appLogger)
+	if err := middleware.InitQueues(cfg, appLogger); err != nil {
+		appLogger.Error("Failed to initialize work queues", logger.String("error", err.Error()))
+		return
+	}
 	summaryQueue := middleware.GetQueue("SummaryServiceQueue")
 
 	// 初始化处理器
 	courseHandler := httpHandlers.NewCourseHandler(
 		courseService,
 		summaryRepo,
-		userService,
+		transcriptRepo,
 		scheduleService,
 		liveCourseService,
 		videoAuthService,
+		videoSigner,
 		appLogger,
 	)
 	summaryHandler := httpHandlers.NewSummaryHandler(
@@ -118,28 +157,78 @@ func main() {
 		summaryQueue,
 		courseService,
 		summaryRepo,
+		transcriptRepo,
 		userService,
 		videoAuthService,
+		videoSigner,
 		ffmpegService,
 		cosService,
-		asrService,
-		openaiService,
+		asrEngine,
+		llmProvider,
+		promptRegistry,
+		liveCourseService,
+		usageService,
+		summaryLimiter,
 		cfg,
 	)
+	jobHandler := httpHandlers.NewJobHandler(summaryQueue, appLogger)
+	adminHandler := httpHandlers.NewAdminHandler(credentialPool, appLogger)
+	promptHandler := httpHandlers.NewPromptHandler(promptRepo, appLogger)
+	usageHandler := httpHandlers.NewUsageHandler(usageService, usageRepo, appLogger)
 	healthHandler := httpHandlers.NewHealthHandler()
 
 	// 设置路由
 	router := http.SetupRouter(
 		courseHandler,
 		summaryHandler,
+		jobHandler,
+		adminHandler,
+		promptHandler,
+		usageHandler,
 		healthHandler,
 		httpMiddleware.ErrorHandler(),
 		httpMiddleware.LoggerMiddleware(appLogger),
+		httpMiddleware.AdminAuth(cfg.AdminToken),
+		httpMiddleware.UserAuth(userService),
+		httpMiddleware.StreamAuth(userService),
 	)
 
 	// 启动服务
-	appLogger.Info("Starting server", logger.String("port", cfg.Port))
-	if err := router.Run(":" + cfg.Port); err != nil {
-		appLogger.Error("Failed to start server", logger.String("error", err.Error()))
+	server := &netHttp.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: router,
 	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 定期重置没有对应任务的生成中状态
+	reaper := summary.NewReaper(courseService, summaryQueue, cfg, appLogger)
+	go reaper.Run(ctx)
+
+	go func() {
+		appLogger.Info("Starting server", logger.String("port", cfg.Port))
+		if err := server.ListenAndServe(); err != nil && !stdErrors.Is(err, netHttp.ErrServerClosed) {
+			appLogger.Error("Failed to start server", logger.String("error", err.Error()))
+			stop()
+		}
+	}()
+
+	// 等待退出信号
+	<-ctx.Done()
+	stop()
+	appLogger.Info("Shutting down server")
+
+	// 停止接收请求，等待处理中的请求完成
+	httpCtx, httpCancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+	defer httpCancel()
+	if err := server.Shutdown(httpCtx); err != nil {
+		appLogger.Error("Failed to shut down server", logger.String("error", err.Error()))
+	}
+
+	// 等待执行中的任务，超时后中断并保留到下次启动
+	queueCtx, queueCancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownGracePeriod)*time.Second)
+	defer queueCancel()
+	middleware.StopQueues(queueCtx)
+
+	appLogger.Info("Server stopped")
 }
@@ -2,6 +2,7 @@ package course
 
 import (
 	"context"
+	"time"
 
 	"iwut-smartclass-backend/internal/domain/course"
 	"iwut-smartclass-backend/internal/domain/errors"
@@ -68,9 +69,28 @@ func (s *Service) UpdateSummaryStatus(ctx context.Context, subID int, status str
 	return nil
 }
 
+// FindStaleGenerating 查找长时间处于生成中状态的课程
+func (s *Service) FindStaleGenerating(ctx context.Context, before time.Time) ([]*course.Course, error) {
+	courses, err := s.courseRepo.FindStaleGenerating(ctx, before)
+	if err != nil {
+		s.logger.Error("failed to find stale courses", logger.String("error", err.Error()))
+		return nil, errors.WrapError(err, "failed to find stale courses")
+	}
+	return courses, nil
+}
+
+// UpdateSummaryPartial 保存生成中的部分摘要，已完成的摘要保持不变
+func (s *Service) UpdateSummaryPartial(ctx context.Context, subID int, partial string) error {
+	if err := s.courseRepo.UpdateSummaryPartial(ctx, subID, partial); err != nil {
+		s.logger.Error("failed to update partial summary", logger.String("error", err.Error()))
+		return errors.WrapError(err, "failed to update partial summary")
+	}
+	return nil
+}
+
 // UpdateSummary 更新摘要数据
-func (s *Service) UpdateSummary(ctx context.Context, subID int, summary, model string, token uint32, user string) error {
-	if err := s.courseRepo.UpdateSummary(ctx, subID, summary, model, token, user); err != nil {
+func (s *Service) UpdateSummary(ctx context.Context, subID int, summary, model, promptVersion string, token uint32, user string) error {
+	if err := s.courseRepo.UpdateSummary(ctx, subID, summary, model, promptVersion, token, user); err != nil {
 		s.logger.Error("failed to update summary", logger.String("error", err.Error()))
 		return errors.WrapError(err, "failed to update summary")
 	}
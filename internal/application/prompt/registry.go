@@ -0,0 +1,154 @@
+package prompt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"text/template"
+
+	"iwut-smartclass-backend/assets"
+	"iwut-smartclass-backend/internal/domain/errors"
+	"iwut-smartclass-backend/internal/domain/prompt"
+	"iwut-smartclass-backend/internal/infrastructure/logger"
+)
+
+// EmbeddedVersion 内置模板的版本号，修改内置模板内容时递增
+const EmbeddedVersion = 1
+
+// 各用途的内置模板
+var embeddedAssets = map[string]string{
+	prompt.KindSummary: "templates/course_summary_prompt.txt",
+	prompt.KindSection: "templates/course_section_prompt.txt",
+	prompt.KindReduce:  "templates/course_reduce_prompt.txt",
+}
+
+// Variables 模板变量，同时作为查找模板的依据
+type Variables struct {
+	CourseID         int
+	CourseName       string
+	Teacher          string
+	Date             string
+	Location         string
+	TenantID         int
+	TranscriptLength int // 转写文本字数
+	TranscriptTokens int // 转写文本估算 Token 数
+	Section          int // 当前分段序号，从 1 开始
+	Sections         int // 分段总数
+}
+
+// Prompt 解析后的模板
+type Prompt struct {
+	Template *prompt.Template
+	parsed   *template.Template
+}
+
+// Ref 返回模板版本标识
+func (p *Prompt) Ref() string {
+	return p.Template.Ref()
+}
+
+// Render 填入变量生成提示词
+func (p *Prompt) Render(vars Variables) (string, error) {
+	var buf bytes.Buffer
+	if err := p.parsed.Execute(&buf, vars); err != nil {
+		return "", errors.NewInternalError(fmt.Sprintf("failed to render prompt %s", p.Ref()), err)
+	}
+	return buf.String(), nil
+}
+
+// Registry 提示词模板注册表，按课程ID、课程名、教师、租户依次查找覆盖模板，最后回退到内置模板
+type Registry struct {
+	repo   prompt.Repository
+	logger logger.Logger
+}
+
+// NewRegistry 创建提示词模板注册表，repo 为空时只使用内置模板
+func NewRegistry(repo prompt.Repository, logger logger.Logger) *Registry {
+	return &Registry{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Resolve 查找最匹配的模板
+func (r *Registry) Resolve(ctx context.Context, kind string, vars Variables) (*Prompt, error) {
+	if !prompt.IsValidKind(kind) {
+		return nil, errors.NewValidationError(fmt.Sprintf("unknown prompt kind %q", kind), nil)
+	}
+
+	if r.repo != nil {
+		for _, candidate := range lookupOrder(vars) {
+			if candidate.value == "" && candidate.scope != prompt.ScopeDefault {
+				continue
+			}
+			t, err := r.repo.FindLatest(ctx, kind, candidate.scope, candidate.value)
+			if err != nil {
+				return nil, errors.NewInternalError("failed to find prompt template", err)
+			}
+			if t == nil {
+				continue
+			}
+			p, err := Parse(t)
+			if err != nil {
+				// 覆盖模板有误时继续回退，避免影响摘要生成
+				r.logger.Warn("invalid prompt template, falling back", logger.String("template", t.Ref()), logger.String("error", err.Error()))
+				continue
+			}
+			return p, nil
+		}
+	}
+
+	return Embedded(kind)
+}
+
+type scopeKey struct {
+	scope string
+	value string
+}
+
+// lookupOrder 返回从具体到通用的作用域
+func lookupOrder(vars Variables) []scopeKey {
+	courseID, tenantID := "", ""
+	if vars.CourseID != 0 {
+		courseID = strconv.Itoa(vars.CourseID)
+	}
+	if vars.TenantID != 0 {
+		tenantID = strconv.Itoa(vars.TenantID)
+	}
+	return []scopeKey{
+		{prompt.ScopeCourseID, courseID},
+		{prompt.ScopeCourseName, vars.CourseName},
+		{prompt.ScopeTeacher, vars.Teacher},
+		{prompt.ScopeTenant, tenantID},
+		{prompt.ScopeDefault, ""},
+	}
+}
+
+// Embedded 读取内置模板
+func Embedded(kind string) (*Prompt, error) {
+	content, err := assets.GetAssets(embeddedAssets[kind])
+	if err != nil {
+		return nil, errors.NewInternalError("failed to read prompt template", err)
+	}
+	return Parse(&prompt.Template{
+		Kind:    kind,
+		Scope:   prompt.ScopeEmbedded,
+		Version: EmbeddedVersion,
+		Content: string(content),
+		Enabled: true,
+	})
+}
+
+// Parse 解析模板内容
+func Parse(t *prompt.Template) (*Prompt, error) {
+	parsed, err := template.New(t.Ref()).Parse(t.Content)
+	if err != nil {
+		return nil, errors.NewValidationError(fmt.Sprintf("invalid prompt template: %v", err), nil)
+	}
+	// 用空变量试渲染，提前发现引用了不存在字段的模板
+	if err := parsed.Execute(&bytes.Buffer{}, Variables{}); err != nil {
+		return nil, errors.NewValidationError(fmt.Sprintf("invalid prompt template: %v", err), nil)
+	}
+	return &Prompt{Template: t, parsed: parsed}, nil
+}
@@ -0,0 +1,56 @@
+package summary
+
+import "iwut-smartclass-backend/internal/infrastructure/logger"
+
+// 摘要流水线阶段，按执行顺序排列
+const (
+	StageNone           = ""
+	StageAudioExtracted = "audio_extracted" // 音频已提取到本地
+	StageUploaded       = "uploaded"        // 音频已上传到 COS
+	StageASRSubmitted   = "asr_submitted"   // ASR 任务已创建
+	StageASRFinished    = "asr_finished"    // ASR 文本已保存
+)
+
+var stageOrder = map[string]int{
+	StageNone:           0,
+	StageAudioExtracted: 1,
+	StageUploaded:       2,
+	StageASRSubmitted:   3,
+	StageASRFinished:    4,
+}
+
+// Checkpoint 摘要流水线检查点，随任务一起持久化，恢复时从最后完成的阶段继续
+type Checkpoint struct {
+	Stage       string `json:"stage"`
+	AudioPath   string `json:"audio_path,omitempty"`
+	ObjectKey   string `json:"object_key,omitempty"`
+	ASRTaskID   uint64 `json:"asr_task_id,omitempty"`
+	ASRKeyIndex int    `json:"asr_key_index,omitempty"`
+	DraftAt     int64  `json:"draft_at,omitempty"` // 重新生成时创建的摘要行的创建时间（Unix 秒），重试时复用该行
+}
+
+// Reached 检查是否已完成指定阶段
+func (c *Checkpoint) Reached(stage string) bool {
+	return stageOrder[c.Stage] >= stageOrder[stage]
+}
+
+// SetCheckpoint 注入检查点持久化回调（由工作队列调用）
+func (j *SummaryJob) SetCheckpoint(save func() error) {
+	j.saveCheckpoint = save
+}
+
+// advance 推进到指定阶段并持久化，持久化失败只记录日志
+func (j *SummaryJob) advance(stage string) {
+	j.Checkpoint.Stage = stage
+	j.persistCheckpoint()
+}
+
+// persistCheckpoint 持久化当前检查点，持久化失败只记录日志
+func (j *SummaryJob) persistCheckpoint() {
+	if j.saveCheckpoint == nil {
+		return
+	}
+	if err := j.saveCheckpoint(); err != nil {
+		j.logger.Warn("failed to persist checkpoint", logger.String("stage", j.Checkpoint.Stage), logger.String("error", err.Error()))
+	}
+}
@@ -0,0 +1,110 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"iwut-smartclass-backend/internal/infrastructure/events"
+	"iwut-smartclass-backend/internal/infrastructure/logger"
+	"iwut-smartclass-backend/internal/middleware"
+)
+
+// 摘要任务进度事件类型
+const (
+	EventQueued          = "queued"            // 排队中
+	EventFetchingAuthKey = "fetching_auth_key" // 获取视频密钥
+	EventExtractingAudio = "extracting_audio"  // 提取音频
+	EventUploading       = "uploading"         // 上传音频
+	EventASRPolling      = "asr_polling"       // 等待语音识别结果
+	EventSummarising     = "summarising"       // 生成摘要
+	EventSummaryDelta    = "summary_delta"     // 摘要增量文本
+	EventRetrying        = "retrying"          // 失败后等待重试
+	EventDone            = "done"              // 完成
+	EventFailed          = "failed"            // 失败
+	EventCancelled       = "cancelled"         // 已取消
+)
+
+// EventTopic 课程摘要进度的事件主题
+func EventTopic(subID int) string {
+	return fmt.Sprintf("summary:%d", subID)
+}
+
+// publish 发布任务进度事件
+func (j *SummaryJob) publish(eventType string, message string, final bool) {
+	events.GetBus().Publish(EventTopic(j.SubID), events.Event{
+		Type:     eventType,
+		Message:  message,
+		Final:    final,
+		Audience: j.audience(),
+		Data: map[string]interface{}{
+			"sub_id": j.SubID,
+			"job_id": j.GetID(),
+			"task":   j.Task,
+		},
+	})
+}
+
+// publishDelta 转发流式生成的增量文本，不作为最后一个事件保留
+func (j *SummaryJob) publishDelta(delta string) {
+	events.GetBus().Publish(EventTopic(j.SubID), events.Event{
+		Type:      EventSummaryDelta,
+		Transient: true,
+		Audience:  j.audience(),
+		Data: map[string]interface{}{
+			"sub_id": j.SubID,
+			"job_id": j.GetID(),
+			"delta":  delta,
+		},
+	})
+}
+
+// audience 重新生成的摘要只属于发起用户，其进度与文本只推送给该用户
+func (j *SummaryJob) audience() string {
+	if j.Task == "regenerate" {
+		return j.Account
+	}
+	return ""
+}
+
+// OnStateChange 队列状态变化时发布对应事件，并按队列的最终结果维护课程摘要状态：
+// 领取执行时标记为生成中，重试之间保持不变，进入死信后重置，取消时由 Cleanup 重置
+func (j *SummaryJob) OnStateChange(state middleware.JobState, err error) {
+	message := ""
+	if err != nil {
+		message = err.Error()
+	}
+
+	switch state {
+	case middleware.JobStateQueued:
+		j.publish(EventQueued, "", false)
+	case middleware.JobStateRunning:
+		j.updateStatus("generating")
+	case middleware.JobStateRetrying:
+		j.publish(EventRetrying, message, false)
+	case middleware.JobStateSucceeded:
+		j.publish(EventDone, "", true)
+	case middleware.JobStateFailed:
+		j.updateStatus("")
+		j.publish(EventFailed, message, true)
+	case middleware.JobStateCancelled:
+		j.publish(EventCancelled, "", true)
+	}
+}
+
+// tracksStatus 是否需要维护课程摘要状态，只有需要识别音频的新摘要任务会标记课程为生成中
+func (j *SummaryJob) tracksStatus() bool {
+	return j.Task == "new" && j.Asr == ""
+}
+
+// updateStatus 更新课程摘要状态
+func (j *SummaryJob) updateStatus(status string) {
+	if !j.tracksStatus() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := j.courseService.UpdateSummaryStatus(ctx, j.SubID, status); err != nil {
+		j.logger.Warn("failed to update summary status", logger.String("job", j.GetID()), logger.String("status", status), logger.String("error", err.Error()))
+	}
+}
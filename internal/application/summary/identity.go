@@ -0,0 +1,80 @@
+package summary
+
+import (
+	stdErrors "errors"
+	"fmt"
+	"time"
+
+	"iwut-smartclass-backend/internal/domain/user"
+	"iwut-smartclass-backend/internal/infrastructure/config"
+	"iwut-smartclass-backend/internal/infrastructure/secret"
+)
+
+// ErrTokenExpired 持久化的令牌已过期，任务无法恢复
+var ErrTokenExpired = stdErrors.New("persisted token expired")
+
+// ErrTokenMissing 任务仍需令牌，但令牌没有持久化（未配置 JOB_TOKEN_KEY）
+var ErrTokenMissing = stdErrors.New("persisted token missing")
+
+// jobIdentity 持久化的用户身份，只保留重建任务所需的字段
+type jobIdentity struct {
+	Account  string `json:"account"`
+	ID       int    `json:"id"`
+	TenantID int    `json:"tenant_id"`
+	SignKey  string `json:"sign_key,omitempty"` // 反转后的手机号，仅用于视频链接签名
+}
+
+func newJobIdentity(u *user.User) *jobIdentity {
+	if u == nil {
+		return nil
+	}
+	return &jobIdentity{
+		Account:  u.Account,
+		ID:       u.ID,
+		TenantID: u.TenantID,
+		SignKey:  u.ReversePhone(),
+	}
+}
+
+// user 还原用户实体，手机号由签名材料反转得到
+func (i *jobIdentity) user() *user.User {
+	u := &user.User{Account: i.Account, ID: i.ID, TenantID: i.TenantID, Phone: i.SignKey}
+	u.Phone = u.ReversePhone()
+	return u
+}
+
+// needsToken 任务后续步骤是否还需要令牌：提取音频需要用令牌换取视频密钥，旧任务还需要用令牌获取用户
+func (j *SummaryJob) needsToken() bool {
+	if j.User == nil || j.User.ID == 0 {
+		return true
+	}
+	return j.Task == "new" && j.Asr == "" && !j.Checkpoint.Reached(StageASRFinished)
+}
+
+// sealToken 用 JOB_TOKEN_KEY 加密令牌，未配置密钥时不保存令牌
+func sealToken(cfg *config.Config, token string) (string, error) {
+	if cfg.JobTokenKey == "" || token == "" {
+		return "", nil
+	}
+	box, err := secret.NewBox(cfg.JobTokenKey)
+	if err != nil {
+		return "", err
+	}
+	return box.Seal(token)
+}
+
+// openToken 解密持久化的令牌并拒绝已过期的令牌
+func openToken(cfg *config.Config, sealed string) (string, error) {
+	box, err := secret.NewBox(cfg.JobTokenKey)
+	if err != nil {
+		return "", fmt.Errorf("JOB_TOKEN_KEY is required to restore the token: %w", err)
+	}
+	token, err := box.Open(sealed)
+	if err != nil {
+		return "", err
+	}
+	if user.TokenExpired(token, time.Now()) {
+		return "", ErrTokenExpired
+	}
+	return token, nil
+}
@@ -2,26 +2,35 @@ package summary
 
 import (
 	"encoding/json"
+	stdErrors "errors"
 	"fmt"
 	"iwut-smartclass-backend/internal/application/course"
+	"iwut-smartclass-backend/internal/application/prompt"
+	"iwut-smartclass-backend/internal/application/usage"
 	"iwut-smartclass-backend/internal/database"
+	"iwut-smartclass-backend/internal/domain/user"
 	"iwut-smartclass-backend/internal/infrastructure/config"
 	"iwut-smartclass-backend/internal/infrastructure/external"
 	"iwut-smartclass-backend/internal/infrastructure/logger"
 	"iwut-smartclass-backend/internal/infrastructure/persistence"
 	"iwut-smartclass-backend/internal/middleware"
+	"time"
 )
 
 func init() {
 	middleware.RegisterGlobalLoader("summary", func(data []byte, cfg *config.Config, logger logger.Logger) (middleware.Job, error) {
 		var jobData struct {
-			Token       string `json:"token"`
-			SubID       int    `json:"sub_id"`
-			Task        string `json:"task"`
-			CourseID    int    `json:"course_id"`
-			CourseName  string `json:"course_name"`
-			VideoURL    string `json:"video_url"`
-			Asr         string `json:"asr"`
+			Token       string       `json:"token"` // 旧版本保存的明文令牌
+			TokenSealed string       `json:"token_sealed"`
+			Account     string       `json:"account"`
+			Identity    *jobIdentity `json:"identity"`
+			SubID       int          `json:"sub_id"`
+			Task        string       `json:"task"`
+			CourseID    int          `json:"course_id"`
+			CourseName  string       `json:"course_name"`
+			VideoURL    string       `json:"video_url"`
+			Asr         string       `json:"asr"`
+			Checkpoint  Checkpoint   `json:"checkpoint"`
 		}
 		if err := json.Unmarshal(data, &jobData); err != nil {
 			return nil, err
@@ -39,28 +48,46 @@ func init() {
 		// 创建仓储
 		courseRepo := persistence.NewCourseRepository(db, appLogger)
 		summaryRepo := persistence.NewSummaryRepository(db, appLogger)
+		transcriptRepo := persistence.NewTranscriptRepository(db, appLogger)
 
 		// 创建应用服务
 		courseService := course.NewService(courseRepo, appLogger)
+		usageService := usage.NewService(persistence.NewUsageRepository(db, appLogger), appLogger)
 
 		// 创建外部服务
-		userService := external.NewUserService(cfg, appLogger)
+		userService := external.SharedUserService(cfg, appLogger)
 		videoAuthService := external.NewVideoAuthService(cfg, appLogger)
+		videoSigner := external.NewVideoURLSignerFromConfig(cfg)
 		ffmpegService := external.NewFFmpegService(appLogger)
-		
+
 		// COS和ASR服务需要根据配置创建
-		cosService, err := external.NewCOSService(cfg.TencentSecretId[0], cfg.TencentSecretKey[0], cfg.BucketUrl, appLogger)
+		cosService, err := external.NewCOSServiceFromConfig(cfg, appLogger)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create COS service: %w", err)
 		}
-		asrService, err := external.NewASRService(cfg.TencentSecretId[0], cfg.TencentSecretKey[0], appLogger)
+		asrEngine, err := external.NewASREngine(cfg, appLogger)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create ASR service: %w", err)
+			return nil, fmt.Errorf("failed to create ASR engine: %w", err)
+		}
+		llmProvider := external.NewLLMProvider(cfg, appLogger)
+		prompts := prompt.NewRegistry(persistence.NewPromptRepository(db, appLogger), appLogger)
+
+		// 旧版本持久化的任务只有账号，执行时再按令牌获取用户
+		userInfo := &user.User{Account: jobData.Account}
+		if jobData.Identity != nil {
+			userInfo = jobData.Identity.user()
+		}
+		token := jobData.Token
+		if jobData.TokenSealed != "" {
+			token, err = openToken(cfg, jobData.TokenSealed)
+			if err != nil && !stdErrors.Is(err, ErrTokenExpired) {
+				return nil, fmt.Errorf("failed to restore token: %w", err)
+			}
 		}
-		openaiService := external.NewOpenAIService(cfg, appLogger)
 
-		return NewSummaryJob(
-			jobData.Token,
+		job := NewSummaryJob(
+			token,
+			userInfo,
 			jobData.SubID,
 			jobData.Task,
 			jobData.CourseID,
@@ -69,14 +96,32 @@ func init() {
 			jobData.Asr,
 			courseService,
 			summaryRepo,
+			transcriptRepo,
 			userService,
 			videoAuthService,
+			videoSigner,
 			ffmpegService,
 			cosService,
-			asrService,
-			openaiService,
+			asrEngine,
+			llmProvider,
+			prompts,
+			usageService,
 			cfg,
 			appLogger,
-		), nil
+		)
+		job.Checkpoint = jobData.Checkpoint
+
+		// 仍需要令牌的任务拒绝使用已过期或缺失的令牌，不再需要的令牌直接丢弃
+		if !job.needsToken() {
+			job.Token = ""
+		} else if token == "" {
+			if jobData.TokenSealed != "" {
+				return nil, ErrTokenExpired
+			}
+			return nil, ErrTokenMissing
+		} else if user.TokenExpired(token, time.Now()) {
+			return nil, ErrTokenExpired
+		}
+		return job, nil
 	})
 }
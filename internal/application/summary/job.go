@@ -2,49 +2,63 @@ package summary
 
 import (
 	"context"
-	"crypto/md5"
+	"crypto/sha1"
+	stdErrors "errors"
 	"fmt"
-	"math/rand"
-	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
+	"unicode/utf8"
 
-	"iwut-smartclass-backend/assets"
 	"iwut-smartclass-backend/internal/application/course"
+	"iwut-smartclass-backend/internal/application/prompt"
+	"iwut-smartclass-backend/internal/application/usage"
 	"iwut-smartclass-backend/internal/domain/errors"
 	"iwut-smartclass-backend/internal/domain/summary"
+	"iwut-smartclass-backend/internal/domain/transcript"
+	"iwut-smartclass-backend/internal/domain/user"
 	"iwut-smartclass-backend/internal/infrastructure/config"
 	"iwut-smartclass-backend/internal/infrastructure/external"
 	"iwut-smartclass-backend/internal/infrastructure/logger"
+	"iwut-smartclass-backend/internal/middleware"
 )
 
 // SummaryJob 摘要生成任务
 type SummaryJob struct {
 	Token      string
+	Account    string
+	User       *user.User // 提交时解析的用户，为空时按 Token 重新获取
 	SubID      int
 	Task       string
 	CourseID   int
 	CourseName string
 	VideoURL   string
 	Asr        string
+	Checkpoint Checkpoint
 
 	// 依赖注入
 	courseService    *course.Service
 	summaryRepo      summary.Repository
-	userService      *external.UserService
+	transcriptRepo   transcript.Repository
+	userService      user.ExternalService
 	videoAuthService *external.VideoAuthService
+	videoSigner      *external.VideoURLSigner
 	ffmpegService    *external.FFmpegService
 	cosService       *external.COSService
-	asrService       *external.ASRService
-	openaiService    *external.OpenAIService
+	asrEngine        external.ASREngine
+	llmProvider      external.LLMProvider
+	prompts          *prompt.Registry
+	usageService     *usage.Service
 	config           *config.Config
 	logger           logger.Logger
+	saveCheckpoint   func() error
 }
 
 // NewSummaryJob 创建摘要任务
 func NewSummaryJob(
 	token string,
+	userInfo *user.User,
 	subID int,
 	task string,
 	courseID int,
@@ -53,17 +67,23 @@ func NewSummaryJob(
 	asr string,
 	courseService *course.Service,
 	summaryRepo summary.Repository,
-	userService *external.UserService,
+	transcriptRepo transcript.Repository,
+	userService user.ExternalService,
 	videoAuthService *external.VideoAuthService,
+	videoSigner *external.VideoURLSigner,
 	ffmpegService *external.FFmpegService,
 	cosService *external.COSService,
-	asrService *external.ASRService,
-	openaiService *external.OpenAIService,
+	asrEngine external.ASREngine,
+	llmProvider external.LLMProvider,
+	prompts *prompt.Registry,
+	usageService *usage.Service,
 	cfg *config.Config,
 	logger logger.Logger,
 ) *SummaryJob {
 	return &SummaryJob{
 		Token:            token,
+		Account:          userInfo.Account,
+		User:             userInfo,
 		SubID:            subID,
 		Task:             task,
 		CourseID:         courseID,
@@ -72,33 +92,57 @@ func NewSummaryJob(
 		Asr:              asr,
 		courseService:    courseService,
 		summaryRepo:      summaryRepo,
+		transcriptRepo:   transcriptRepo,
 		userService:      userService,
 		videoAuthService: videoAuthService,
+		videoSigner:      videoSigner,
 		ffmpegService:    ffmpegService,
 		cosService:       cosService,
-		asrService:       asrService,
-		openaiService:    openaiService,
+		asrEngine:        asrEngine,
+		llmProvider:      llmProvider,
+		prompts:          prompts,
+		usageService:     usageService,
 		config:           cfg,
 		logger:           logger,
 	}
 }
 
 // GetID 获取任务ID
+// 相同课程的相同任务共用一个ID，重复提交会复用正在执行的任务；
+// 重新生成的摘要归属于发起用户，因此额外按账号区分
 func (j *SummaryJob) GetID() string {
-	return fmt.Sprintf("summary-%d", j.SubID)
+	if j.Task == "regenerate" && j.Account != "" {
+		accountHash := fmt.Sprintf("%x", sha1.Sum([]byte(j.Account)))
+		return fmt.Sprintf("summary-%d-%s-%s", j.SubID, j.Task, accountHash[:8])
+	}
+	return fmt.Sprintf("summary-%d-%s", j.SubID, j.Task)
 }
 
 // GetData 获取任务数据（用于序列化）
+// 只保存重建任务所需的用户身份；令牌仅在后续步骤仍需要时加密保存
 func (j *SummaryJob) GetData() interface{} {
-	return map[string]interface{}{
-		"token":       j.Token,
+	data := map[string]interface{}{
+		"account":     j.Account,
 		"sub_id":      j.SubID,
 		"task":        j.Task,
 		"course_id":   j.CourseID,
 		"course_name": j.CourseName,
 		"video_url":   j.VideoURL,
 		"asr":         j.Asr,
+		"checkpoint":  j.Checkpoint,
+	}
+	if j.User != nil && j.User.ID != 0 {
+		data["identity"] = newJobIdentity(j.User)
+	}
+	if j.needsToken() {
+		sealed, err := sealToken(j.config, j.Token)
+		if err != nil {
+			j.logger.Warn("failed to seal token, job cannot resume after restart", logger.String("job", j.GetID()), logger.String("error", err.Error()))
+		} else if sealed != "" {
+			data["token_sealed"] = sealed
+		}
 	}
+	return data
 }
 
 // GetType 获取任务类型
@@ -106,124 +150,55 @@ func (j *SummaryJob) GetType() string {
 	return "summary"
 }
 
-// Execute 执行任务
-func (j *SummaryJob) Execute() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+// GetLabels 获取任务标签（用于状态查询过滤）
+func (j *SummaryJob) GetLabels() map[string]string {
+	return map[string]string{
+		"sub_id": fmt.Sprintf("%d", j.SubID),
+		"task":   j.Task,
+	}
+}
+
+// GetPriority 获取任务优先级，基于已有识别结果重新生成只需调用 LLM，优先执行
+func (j *SummaryJob) GetPriority() int {
+	if j.Task == "regenerate" {
+		return middleware.PriorityHigh
+	}
+	return middleware.PriorityLow
+}
+
+// GetOwner 获取提交任务的用户（用于按用户公平调度）
+func (j *SummaryJob) GetOwner() string {
+	return j.Account
+}
+
+// Execute 执行任务，ctx 取消时中断音频提取、ASR 轮询与 LLM 调用
+func (j *SummaryJob) Execute(jobCtx context.Context) error {
+	ctx, cancel := context.WithTimeout(jobCtx, 15*time.Minute)
 	defer cancel()
 
 	// 获取用户信息
-	userInfo, err := j.userService.GetUserInfo(j.Token)
+	userInfo, err := j.resolveUser(ctx)
 	if err != nil {
 		j.logger.Error("failed to get user info", logger.String("error", err.Error()))
 		return err
 	}
 
 	var asrText string
+	var draft *summary.Summary
 
 	if j.Task == "new" && j.Asr == "" {
-		// 更新状态为生成中
-		if err := j.courseService.UpdateSummaryStatus(ctx, j.SubID, "generating"); err != nil {
-			j.logger.Error("failed to update summary status", logger.String("error", err.Error()))
-			return err
-		}
-
-		// 获取视频密钥
-		authKey, err := j.videoAuthService.GetVideoAuthKey(j.Token, j.CourseID, j.SubID)
-		if err != nil {
-			j.logger.Error("failed to get video auth key", logger.String("error", err.Error()))
-			_ = j.courseService.UpdateSummaryStatus(ctx, j.SubID, "")
-			return err
-		}
-
-		// 拼接带密钥的视频链接
-		parsedURL, err := url.Parse(j.VideoURL)
-		if err != nil {
-			j.logger.Error("failed to parse video URL", logger.String("error", err.Error()))
-			_ = j.courseService.UpdateSummaryStatus(ctx, j.SubID, "")
-			return errors.NewInternalError("failed to parse video URL", err)
-		}
-		timestamp := time.Now().Unix()
-		md5Input := fmt.Sprintf("%s%d%d%s%d", parsedURL.Path, userInfo.ID, userInfo.TenantID, userInfo.ReversePhone(), timestamp)
-		md5Hash := fmt.Sprintf("%x", md5.Sum([]byte(md5Input)))
-		videoAuth := fmt.Sprintf("auth_key=%s&t=%d-%d-%s", authKey, userInfo.ID, timestamp, md5Hash)
-		video := fmt.Sprintf("%s?%s", j.VideoURL, videoAuth)
-
-		// 直接用 SubID 生成稳定文件名，避免数据库字段
-		audioID := fmt.Sprintf("%d", j.SubID)
-		audioFileName := audioID + ".aac"
-		audioFilePath := filepath.Join("temp", "audio", audioFileName)
-		tmpAudioPath := audioFilePath + ".tmp"
-
-		// 创建目录并清理可能的残留文件
-		if err := os.MkdirAll(filepath.Dir(audioFilePath), 0755); err != nil {
-			j.logger.Error("failed to create directory", logger.String("error", err.Error()))
-			_ = j.courseService.UpdateSummaryStatus(ctx, j.SubID, "")
-			return errors.NewInternalError("failed to create directory", err)
-		}
-		_ = os.Remove(tmpAudioPath)
-
-		// 转换视频为音频，先写入临时文件避免并发干扰
-		convertCtx, convertCancel := context.WithTimeout(ctx, 5*time.Minute)
-		err = j.ffmpegService.ConvertVideoToAudio(convertCtx, video, tmpAudioPath)
-		convertCancel()
+		asrText, err = j.transcribe(ctx, userInfo)
 		if err != nil {
-			_ = os.Remove(tmpAudioPath)
-			j.logger.Error("failed to convert video to audio", logger.String("error", err.Error()))
-			_ = j.courseService.UpdateSummaryStatus(ctx, j.SubID, "")
 			return err
 		}
-
-		// 原子替换最终文件，避免其他线程读取半成品
-		_ = os.Remove(audioFilePath)
-		if err := os.Rename(tmpAudioPath, audioFilePath); err != nil {
-			j.logger.Error("failed to finalize audio file", logger.String("error", err.Error()))
-			_ = os.Remove(tmpAudioPath)
-			_ = j.courseService.UpdateSummaryStatus(ctx, j.SubID, "")
-			return errors.NewInternalError("failed to finalize audio file", err)
-		}
-
-		// 上传到 COS
-		err = j.cosService.UploadFile(audioFilePath, audioFileName)
-		if err != nil {
-			j.logger.Error("failed to upload file", logger.String("error", err.Error()))
-			_ = j.courseService.UpdateSummaryStatus(ctx, j.SubID, "")
-			return err
-		}
-
-		bucketFilePath := j.config.BucketUrl + "/" + audioFileName
-
-		// ASR 识别
-		randIdx := rand.Intn(len(j.config.TencentSecretId))
-		asrSvc, err := external.NewASRService(j.config.TencentSecretId[randIdx], j.config.TencentSecretKey[randIdx], j.logger)
-		if err != nil {
-			j.logger.Error("failed to create ASR service", logger.String("error", err.Error()))
-			_ = j.courseService.UpdateSummaryStatus(ctx, j.SubID, "")
-			return err
-		}
-
-		asrText, err = asrSvc.Recognize(bucketFilePath)
-		if err != nil {
-			j.logger.Error("failed to recognize audio", logger.String("error", err.Error()))
-			_ = j.courseService.UpdateSummaryStatus(ctx, j.SubID, "")
-			return err
-		}
-
-		// 保存 ASR 结果
-		if err := j.courseService.UpdateAsr(ctx, j.SubID, asrText); err != nil {
-			j.logger.Error("failed to save ASR", logger.String("error", err.Error()))
-			_ = j.courseService.UpdateSummaryStatus(ctx, j.SubID, "")
-			return err
-		}
-
-		// 清理文件
-		j.logger.Info("deleting temporary files", logger.String("file", audioFileName))
-		_ = j.cosService.DeleteFile(audioFileName)
-		_ = os.Remove(audioFilePath)
 	}
 
-	if j.Task == "new" && j.Asr != "" {
+	if j.Task == "new" {
 		// 使用已有的ASR文本
-		asrText = j.Asr
+		if j.Asr != "" {
+			asrText = j.Asr
+		}
+		// 识别结果为空时没有可总结的内容
 		if asrText == "" {
 			j.logger.Error("ASR text is empty")
 			return errors.NewInternalError("ASR text is empty", fmt.Errorf("ASR text is empty"))
@@ -231,8 +206,8 @@ func (j *SummaryJob) Execute() error {
 	}
 
 	if j.Task == "regenerate" {
-		// 初始化Summary行
-		_, err := j.summaryRepo.InitNewSummary(ctx, j.SubID, userInfo.Account)
+		// 初始化Summary行，重试时复用首次执行创建的行
+		draft, err = j.draft(ctx, userInfo.Account)
 		if err != nil {
 			j.logger.Error("failed to init new summary", logger.String("error", err.Error()))
 			return err
@@ -251,24 +226,17 @@ func (j *SummaryJob) Execute() error {
 		}
 	}
 
-	// 读取提示词
-	promptTemplate, err := assets.GetAssets("templates/course_summary_prompt.txt")
-	if err != nil {
-		j.logger.Error("failed to read prompt template", logger.String("error", err.Error()))
-		return errors.NewInternalError("failed to read prompt template", err)
-	}
-	prompt := fmt.Sprintf(string(promptTemplate), j.CourseName)
-
 	// 生成摘要
-	summaryText, token, err := j.openaiService.CallOpenAI(prompt, asrText)
+	j.publish(EventSummarising, "", false)
+	result, err := j.generate(ctx, userInfo, asrText, draft)
 	if err != nil {
-		j.logger.Error("failed to call OpenAI", logger.String("error", err.Error()))
+		j.logger.Error("failed to generate summary", logger.String("error", err.Error()))
 		return err
 	}
 
 	// 保存摘要
 	if j.Task == "new" {
-		err = j.courseService.UpdateSummary(ctx, j.SubID, summaryText, j.config.OpenaiModel, token, userInfo.Account)
+		err = j.courseService.UpdateSummary(ctx, j.SubID, result.Summary, result.Model, result.PromptVersion, result.Token, userInfo.Account)
 		if err != nil {
 			j.logger.Error("failed to save summary", logger.String("error", err.Error()))
 			return err
@@ -276,19 +244,13 @@ func (j *SummaryJob) Execute() error {
 	}
 
 	if j.Task == "regenerate" {
-		// 查找已存在的摘要
-		summaries, err := j.summaryRepo.FindBySubIDAndUser(ctx, j.SubID, userInfo.Account)
-		if err != nil || len(summaries) == 0 {
-			j.logger.Error("failed to find summary", logger.String("error", err.Error()))
-			return errors.NewInternalError("failed to find summary", err)
-		}
-
-		// 更新最新的摘要
-		summaryEntity := summaries[0]
-		summaryEntity.Summary = summaryText
-		summaryEntity.Model = j.config.OpenaiModel
-		summaryEntity.Token = token
-		if err := j.summaryRepo.Update(ctx, summaryEntity); err != nil {
+		// 写入本次的摘要行，成功前摘要保持为空，读取时仍显示之前的摘要
+		draft.Summary = result.Summary
+		draft.Partial = ""
+		draft.Model = result.Model
+		draft.PromptVersion = result.PromptVersion
+		draft.Token = result.Token
+		if err := j.summaryRepo.Update(ctx, draft); err != nil {
 			j.logger.Error("failed to update summary", logger.String("error", err.Error()))
 			return err
 		}
@@ -296,3 +258,311 @@ func (j *SummaryJob) Execute() error {
 
 	return nil
 }
+
+// draft 返回本任务的新摘要行，首次执行时创建并将创建时间记录到检查点，之后的执行复用该行
+func (j *SummaryJob) draft(ctx context.Context, account string) (*summary.Summary, error) {
+	if j.Checkpoint.DraftAt != 0 {
+		return &summary.Summary{
+			User:     account,
+			SubID:    j.SubID,
+			CreateAt: time.Unix(j.Checkpoint.DraftAt, 0),
+		}, nil
+	}
+
+	draft, err := j.summaryRepo.InitNewSummary(ctx, j.SubID, account)
+	if err != nil {
+		return nil, err
+	}
+	j.Checkpoint.DraftAt = draft.CreateAt.Unix()
+	j.persistCheckpoint()
+	return draft, nil
+}
+
+// resolveUser 返回提交任务时解析的用户，旧版本持久化的任务没有用户信息时按 Token 获取
+func (j *SummaryJob) resolveUser(ctx context.Context) (*user.User, error) {
+	if j.User != nil && j.User.ID != 0 {
+		return j.User, nil
+	}
+	userInfo, err := j.userService.GetUserInfo(ctx, j.Token)
+	if err != nil {
+		return nil, err
+	}
+	j.User = userInfo
+	return userInfo, nil
+}
+
+// generate 调用 LLM 生成摘要，长文本分段总结后合并，流式输出时转发增量文本并定期保存部分结果
+func (j *SummaryJob) generate(ctx context.Context, userInfo *user.User, asrText string, draft *summary.Summary) (Result, error) {
+	vars, err := j.promptVariables(ctx, userInfo, asrText)
+	if err != nil {
+		return Result{}, err
+	}
+	strategy := NewStrategy(asrText, j.config.SummaryChunkTokens, j.config.SummaryChunkOverlap, j.prompts, vars, j.complete, func(message string) {
+		j.publish(EventSummarising, message, false)
+	})
+
+	interval := time.Duration(j.config.SummaryFlushInterval) * time.Second
+	var partial strings.Builder
+	lastFlush := time.Now()
+	result, err := strategy.Summarize(ctx, asrText, func(delta string) {
+		partial.WriteString(delta)
+		j.publishDelta(delta)
+		if interval > 0 && time.Since(lastFlush) >= interval {
+			j.savePartial(ctx, partial.String(), draft)
+			lastFlush = time.Now()
+		}
+	})
+	if err != nil && result.Summary != "" {
+		// 保留已生成的部分，避免接近完成时超时丢失全部内容
+		j.savePartial(context.Background(), result.Summary, draft)
+	}
+	return result, err
+}
+
+// promptVariables 根据课程信息生成提示词模板变量
+func (j *SummaryJob) promptVariables(ctx context.Context, userInfo *user.User, asrText string) (prompt.Variables, error) {
+	courseEntity, err := j.courseService.GetCourse(ctx, j.SubID)
+	if err != nil {
+		j.logger.Error("failed to get course", logger.String("error", err.Error()))
+		return prompt.Variables{}, err
+	}
+	return prompt.Variables{
+		CourseID:         j.CourseID,
+		CourseName:       j.CourseName,
+		Teacher:          courseEntity.Teacher,
+		Date:             courseEntity.Date,
+		Location:         courseEntity.Location,
+		TenantID:         userInfo.TenantID,
+		TranscriptLength: utf8.RuneCountInString(asrText),
+		TranscriptTokens: EstimateTokens(asrText),
+	}, nil
+}
+
+// complete 调用 LLM，启用流式输出且需要增量文本时使用流式接口，每次调用的 Token 用量都计入台账
+func (j *SummaryJob) complete(ctx context.Context, prompt, input string, onDelta func(delta string)) (external.LLMResult, error) {
+	var result external.LLMResult
+	var err error
+	if !j.config.OpenaiStream || onDelta == nil {
+		result, err = j.llmProvider.Complete(ctx, prompt, input)
+	} else {
+		result, err = j.llmProvider.Stream(ctx, prompt, input, onDelta)
+	}
+
+	// 任务被取消时已消耗的 Token 仍需记录。流式输出中途失败或服务未返回用量时，
+	// 按提示词与已收到的文本估算
+	promptTokens, completionTokens := int64(result.PromptTokens), int64(result.CompletionTokens)
+	if promptTokens == 0 && completionTokens == 0 && result.Content != "" {
+		promptTokens = int64(EstimateTokens(prompt) + EstimateTokens(input))
+		completionTokens = int64(EstimateTokens(result.Content))
+	}
+	if j.User != nil {
+		if recordErr := j.usageService.RecordLLM(context.WithoutCancel(ctx), j.User, result.Source(), promptTokens, completionTokens); recordErr != nil {
+			j.logger.Warn("failed to record LLM usage", logger.String("job", j.GetID()), logger.String("error", recordErr.Error()))
+		}
+	}
+	return result, err
+}
+
+// savePartial 保存部分摘要，重新生成时写入新摘要行，否则写入课程。
+// 部分摘要与完成的摘要分开保存，生成失败时不会覆盖已有摘要或被当作已完成
+func (j *SummaryJob) savePartial(ctx context.Context, text string, draft *summary.Summary) {
+	var err error
+	if draft != nil {
+		draft.Partial = text
+		err = j.summaryRepo.Update(ctx, draft)
+	} else {
+		err = j.courseService.UpdateSummaryPartial(ctx, j.SubID, text)
+	}
+	if err != nil {
+		j.logger.Warn("failed to save partial summary", logger.String("error", err.Error()))
+	}
+}
+
+// transcribe 执行音频提取、上传与 ASR，已完成的阶段直接跳过
+func (j *SummaryJob) transcribe(ctx context.Context, userInfo *user.User) (string, error) {
+	cp := &j.Checkpoint
+	if cp.Stage != StageNone {
+		j.logger.Info("resuming summary job", logger.String("job", j.GetID()), logger.String("stage", cp.Stage))
+	}
+
+	// 已保存 ASR 结果，直接读取
+	if cp.Reached(StageASRFinished) {
+		courseEntity, err := j.courseService.GetCourse(ctx, j.SubID)
+		if err != nil {
+			j.logger.Error("failed to get course", logger.String("error", err.Error()))
+			return "", err
+		}
+		if courseEntity.HasAsr() {
+			return courseEntity.Asr, nil
+		}
+		j.logger.Warn("checkpointed ASR text missing, restarting pipeline", logger.String("job", j.GetID()))
+		*cp = Checkpoint{}
+	}
+
+	// 直接用 SubID 生成稳定文件名，避免数据库字段
+	audioFileName := fmt.Sprintf("%d.aac", j.SubID)
+
+	// 提取音频，本地文件丢失且尚未上传时需要重新提取
+	if !cp.Reached(StageAudioExtracted) || (!cp.Reached(StageUploaded) && !fileExists(cp.AudioPath)) {
+		audioFilePath, err := j.extractAudio(ctx, userInfo, audioFileName)
+		if err != nil {
+			return "", err
+		}
+		cp.AudioPath = audioFilePath
+		j.advance(StageAudioExtracted)
+	}
+
+	// 上传到 COS，直接接收文件的引擎跳过
+	audio := external.ASRAudio{Path: cp.AudioPath}
+	if j.asrEngine.RequiresURL() {
+		if !cp.Reached(StageUploaded) {
+			j.publish(EventUploading, "", false)
+			if j.cosService == nil {
+				return "", errors.NewInternalError("COS is not configured", fmt.Errorf("ASR engine %s requires an uploaded file", j.asrEngine.Name()))
+			}
+			if err := j.cosService.UploadFile(ctx, cp.AudioPath, audioFileName); err != nil {
+				j.logger.Error("failed to upload file", logger.String("error", err.Error()))
+				return "", err
+			}
+			cp.ObjectKey = audioFileName
+			j.advance(StageUploaded)
+		}
+		audio.URL = j.config.BucketUrl + "/" + cp.ObjectKey
+	}
+
+	// 识别音频，异步任务创建后记录任务ID与密钥序号，恢复时继续查询
+	var task *external.ASRTask
+	if cp.Reached(StageASRSubmitted) {
+		task = &external.ASRTask{ID: cp.ASRTaskID, KeyIndex: cp.ASRKeyIndex}
+	}
+	j.publish(EventASRPolling, "", false)
+	asrResult, err := j.asrEngine.Transcribe(ctx, audio, task, func(submitted external.ASRTask) {
+		cp.ASRTaskID = submitted.ID
+		cp.ASRKeyIndex = submitted.KeyIndex
+		j.advance(StageASRSubmitted)
+	})
+	if err != nil {
+		j.logger.Error("failed to recognize audio", logger.String("engine", j.asrEngine.Name()), logger.String("error", err.Error()))
+		if stdErrors.Is(err, external.ErrASRTaskFailed) {
+			// 任务已在服务端失败，重试时重新创建
+			cp.ASRTaskID = 0
+			j.advance(StageUploaded)
+		}
+		return "", err
+	}
+
+	j.recordASR(ctx, userInfo, asrResult, cp.AudioPath)
+
+	// 保存 ASR 结果，带时间戳的片段保存失败不影响摘要生成
+	asrText := asrResult.Text
+	if err := j.transcriptRepo.Replace(ctx, j.SubID, asrResult.Segments); err != nil {
+		j.logger.Warn("failed to save transcript segments", logger.String("error", err.Error()))
+	}
+	if err := j.courseService.UpdateAsr(ctx, j.SubID, asrText); err != nil {
+		j.logger.Error("failed to save ASR", logger.String("error", err.Error()))
+		return "", err
+	}
+	j.advance(StageASRFinished)
+
+	// 清理文件
+	j.logger.Info("deleting temporary files", logger.String("file", audioFileName))
+	if cp.ObjectKey != "" && j.cosService != nil {
+		_ = j.cosService.DeleteFile(ctx, cp.ObjectKey)
+	}
+	_ = os.Remove(cp.AudioPath)
+
+	return asrText, nil
+}
+
+// recordASR 记录语音识别用量，引擎没有返回音频时长时用 ffprobe 读取本地音频的时长
+func (j *SummaryJob) recordASR(ctx context.Context, userInfo *user.User, result external.ASRResult, audioPath string) {
+	duration := result.Duration()
+	if duration == 0 && fileExists(audioPath) {
+		probed, err := j.ffmpegService.ProbeDuration(ctx, audioPath)
+		if err != nil {
+			j.logger.Warn("failed to probe audio duration", logger.String("job", j.GetID()), logger.String("error", err.Error()))
+		}
+		duration = probed
+	}
+	if err := j.usageService.RecordASR(ctx, userInfo, j.asrEngine.Name(), duration); err != nil {
+		j.logger.Warn("failed to record ASR usage", logger.String("job", j.GetID()), logger.String("error", err.Error()))
+	}
+}
+
+// extractAudio 获取视频密钥并提取音频到本地，返回音频路径
+func (j *SummaryJob) extractAudio(ctx context.Context, userInfo *user.User, audioFileName string) (string, error) {
+	// 获取视频密钥
+	j.publish(EventFetchingAuthKey, "", false)
+	authKey, err := j.videoAuthService.GetVideoAuthKey(ctx, j.Token, j.CourseID, j.SubID)
+	if err != nil {
+		j.logger.Error("failed to get video auth key", logger.String("error", err.Error()))
+		return "", err
+	}
+
+	// 拼接带密钥的视频链接
+	signed, err := j.videoSigner.Sign(j.VideoURL, userInfo, authKey)
+	if err != nil {
+		j.logger.Error("failed to sign video URL", logger.String("error", err.Error()))
+		return "", err
+	}
+	video := signed.URL
+
+	audioFilePath := filepath.Join("temp", "audio", audioFileName)
+	tmpAudioPath := audioFilePath + ".tmp"
+
+	// 创建目录并清理可能的残留文件
+	if err := os.MkdirAll(filepath.Dir(audioFilePath), 0755); err != nil {
+		j.logger.Error("failed to create directory", logger.String("error", err.Error()))
+		return "", errors.NewInternalError("failed to create directory", err)
+	}
+	_ = os.Remove(tmpAudioPath)
+
+	// 转换视频为音频，先写入临时文件避免并发干扰
+	j.publish(EventExtractingAudio, "", false)
+	convertCtx, convertCancel := context.WithTimeout(ctx, 5*time.Minute)
+	err = j.ffmpegService.ConvertVideoToAudio(convertCtx, video, tmpAudioPath)
+	convertCancel()
+	if err != nil {
+		_ = os.Remove(tmpAudioPath)
+		j.logger.Error("failed to convert video to audio", logger.String("error", err.Error()))
+		return "", err
+	}
+
+	// 原子替换最终文件，避免其他线程读取半成品
+	_ = os.Remove(audioFilePath)
+	if err := os.Rename(tmpAudioPath, audioFilePath); err != nil {
+		j.logger.Error("failed to finalize audio file", logger.String("error", err.Error()))
+		_ = os.Remove(tmpAudioPath)
+		return "", errors.NewInternalError("failed to finalize audio file", err)
+	}
+
+	return audioFilePath, nil
+}
+
+// Cleanup 任务取消后重置摘要状态并删除临时音频与 COS 文件
+func (j *SummaryJob) Cleanup(ctx context.Context) {
+	cp := &j.Checkpoint
+
+	if j.tracksStatus() {
+		if err := j.courseService.UpdateSummaryStatus(ctx, j.SubID, ""); err != nil {
+			j.logger.Warn("failed to reset summary status", logger.String("error", err.Error()))
+		}
+	}
+
+	if cp.ObjectKey != "" && j.cosService != nil && !cp.Reached(StageASRFinished) {
+		j.logger.Info("deleting uploaded audio", logger.String("file", cp.ObjectKey))
+		_ = j.cosService.DeleteFile(ctx, cp.ObjectKey)
+	}
+	audioFilePath := filepath.Join("temp", "audio", fmt.Sprintf("%d.aac", j.SubID))
+	_ = os.Remove(audioFilePath)
+	_ = os.Remove(audioFilePath + ".tmp")
+	*cp = Checkpoint{}
+}
+
+func fileExists(path string) bool {
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,133 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"iwut-smartclass-backend/internal/application/course"
+	"iwut-smartclass-backend/internal/infrastructure/config"
+	"iwut-smartclass-backend/internal/infrastructure/logger"
+	"iwut-smartclass-backend/internal/middleware"
+)
+
+// Reaper 定期检查长时间处于生成中状态、但队列中已没有对应任务的课程
+type Reaper struct {
+	courseService *course.Service
+	queue         *middleware.WorkQueue
+	config        *config.Config
+	logger        logger.Logger
+}
+
+// NewReaper 创建摘要状态检查器
+func NewReaper(courseService *course.Service, queue *middleware.WorkQueue, cfg *config.Config, logger logger.Logger) *Reaper {
+	return &Reaper{
+		courseService: courseService,
+		queue:         queue,
+		config:        cfg,
+		logger:        logger,
+	}
+}
+
+// Run 按配置的间隔执行检查，直到 ctx 取消
+func (r *Reaper) Run(ctx context.Context) {
+	interval := time.Duration(r.config.SummaryReaperInterval) * time.Second
+	if interval <= 0 {
+		r.logger.Info("summary status reaper disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.Reconcile(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile 检查一次过期的生成中状态：有死信任务且允许重新入队时重新入队，否则重置状态
+func (r *Reaper) Reconcile(ctx context.Context) {
+	staleAfter := time.Duration(r.config.SummaryStaleAfter) * time.Second
+	courses, err := r.courseService.FindStaleGenerating(ctx, time.Now().Add(-staleAfter))
+	if err != nil || len(courses) == 0 {
+		return
+	}
+
+	// 无法确认队列状态时跳过本轮，避免重置仍在执行的任务
+	statuses, err := r.queue.ListJobs(nil)
+	if err != nil {
+		r.logger.Warn("failed to list jobs, skipping reaper cycle", logger.String("error", err.Error()))
+		return
+	}
+
+	deadJobs, err := r.queue.ListDeadJobs()
+	if err != nil {
+		r.logger.Warn("failed to list dead jobs", logger.String("error", err.Error()))
+	}
+
+	for _, c := range courses {
+		subID := fmt.Sprintf("%d", c.SubID)
+		if hasActiveJob(statuses, subID) {
+			continue
+		}
+
+		if r.config.SummaryReaperRequeue && r.requeue(subID, deadJobs) {
+			continue
+		}
+
+		// summary_data 只在生成成功时写入，有内容说明之前的摘要完整，恢复为完成状态；
+		// 部分摘要保存在 summary_partial 中，不会被当作完成的摘要
+		status := ""
+		if c.SummaryData != "" {
+			status = "finished"
+		}
+		if err := r.courseService.UpdateSummaryStatus(ctx, c.SubID, status); err != nil {
+			continue
+		}
+		r.logger.Info("reset stale summary status",
+			logger.String("sub_id", subID),
+			logger.String("status", status),
+			logger.String("generating_since", formatStatusTime(c.SummaryStatusAt)),
+		)
+	}
+}
+
+// hasActiveJob 检查课程是否还有未结束的任务
+func hasActiveJob(statuses []*middleware.JobStatus, subID string) bool {
+	for _, status := range statuses {
+		if status.Labels["sub_id"] == subID && !status.IsFinished() {
+			return true
+		}
+	}
+	return false
+}
+
+// requeue 重新入队课程最近的死信任务，成功时返回 true。
+// 重新入队次数随任务记录持久化，已重新入队过的任务再次进入死信后不再自动重新入队，重启后同样生效
+func (r *Reaper) requeue(subID string, deadJobs []*middleware.JobRecord) bool {
+	for _, dead := range deadJobs {
+		if dead.Labels["sub_id"] != subID || dead.Labels["task"] != "new" || dead.Requeues > 0 {
+			continue
+		}
+
+		if err := r.queue.RequeueDeadJob(dead.ID); err != nil {
+			r.logger.Warn("failed to requeue orphaned summary job", logger.String("sub_id", subID), logger.String("job", dead.ID), logger.String("error", err.Error()))
+			return false
+		}
+		r.logger.Info("requeued orphaned summary job", logger.String("sub_id", subID), logger.String("job", dead.ID))
+		return true
+	}
+	return false
+}
+
+func formatStatusTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,217 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"iwut-smartclass-backend/internal/application/prompt"
+	domainPrompt "iwut-smartclass-backend/internal/domain/prompt"
+	"iwut-smartclass-backend/internal/infrastructure/external"
+)
+
+// Completer 调用 LLM 完成一次对话，onDelta 非空时转发增量文本
+type Completer func(ctx context.Context, prompt, input string, onDelta func(delta string)) (external.LLMResult, error)
+
+// Result 摘要结果
+type Result struct {
+	Summary       string
+	Token         uint32 // 所有调用消耗的 Token 总数
+	Model         string // 生成最终文本的服务与模型
+	PromptVersion string // 生成摘要所用的模板版本
+}
+
+// Strategy 摘要策略，出错时 Result 中保留已生成的部分
+type Strategy interface {
+	Summarize(ctx context.Context, transcript string, onDelta func(delta string)) (Result, error)
+}
+
+// NewStrategy 根据转写文本长度选择摘要策略，chunkTokens 为 0 时不分段
+func NewStrategy(transcript string, chunkTokens, overlap int, prompts *prompt.Registry, vars prompt.Variables, complete Completer, progress func(message string)) Strategy {
+	if chunkTokens <= 0 || EstimateTokens(transcript) <= chunkTokens {
+		return &singlePassStrategy{complete: complete, prompts: prompts, vars: vars}
+	}
+	return &mapReduceStrategy{
+		complete:    complete,
+		prompts:     prompts,
+		vars:        vars,
+		chunkTokens: chunkTokens,
+		overlap:     overlap,
+		progress:    progress,
+	}
+}
+
+// singlePassStrategy 一次调用生成摘要
+type singlePassStrategy struct {
+	complete Completer
+	prompts  *prompt.Registry
+	vars     prompt.Variables
+}
+
+// Summarize 生成摘要
+func (s *singlePassStrategy) Summarize(ctx context.Context, transcript string, onDelta func(delta string)) (Result, error) {
+	p, text, err := render(ctx, s.prompts, domainPrompt.KindSummary, s.vars)
+	if err != nil {
+		return Result{}, err
+	}
+	completion, err := s.complete(ctx, text, transcript, onDelta)
+	return Result{
+		Summary:       completion.Content,
+		Token:         completion.Token,
+		Model:         completion.Source(),
+		PromptVersion: p.Ref(),
+	}, err
+}
+
+// mapReduceStrategy 将长文本分段总结，再合并为最终摘要
+type mapReduceStrategy struct {
+	complete    Completer
+	prompts     *prompt.Registry
+	vars        prompt.Variables
+	chunkTokens int
+	overlap     int
+	progress    func(message string)
+}
+
+// Summarize 逐段生成要点后合并，只有合并阶段转发增量文本
+func (s *mapReduceStrategy) Summarize(ctx context.Context, transcript string, onDelta func(delta string)) (Result, error) {
+	chunks := SplitTranscript(transcript, s.chunkTokens, s.overlap)
+
+	var result Result
+	var sectionRef string
+	sections := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		s.report(fmt.Sprintf("summarising section %d/%d", i+1, len(chunks)))
+		vars := s.vars
+		vars.Section = i + 1
+		vars.Sections = len(chunks)
+		p, text, err := render(ctx, s.prompts, domainPrompt.KindSection, vars)
+		if err != nil {
+			return result, err
+		}
+		sectionRef = p.Ref()
+		completion, err := s.complete(ctx, text, chunk, nil)
+		result.Token += completion.Token
+		if err != nil {
+			return result, err
+		}
+		sections = append(sections, fmt.Sprintf("## 第 %d 段\n\n%s", i+1, strings.TrimSpace(completion.Content)))
+	}
+
+	s.report(fmt.Sprintf("merging %d sections", len(sections)))
+	vars := s.vars
+	vars.Sections = len(chunks)
+	p, text, err := render(ctx, s.prompts, domainPrompt.KindReduce, vars)
+	if err != nil {
+		return result, err
+	}
+	completion, err := s.complete(ctx, text, strings.Join(sections, "\n\n"), onDelta)
+	result.Summary = completion.Content
+	result.Token += completion.Token
+	result.Model = completion.Source()
+	result.PromptVersion = sectionRef + "+" + p.Ref()
+	return result, err
+}
+
+// report 报告分段进度
+func (s *mapReduceStrategy) report(message string) {
+	if s.progress != nil {
+		s.progress(message)
+	}
+}
+
+// render 查找模板并生成提示词
+func render(ctx context.Context, prompts *prompt.Registry, kind string, vars prompt.Variables) (*prompt.Prompt, string, error) {
+	p, err := prompts.Resolve(ctx, kind, vars)
+	if err != nil {
+		return nil, "", err
+	}
+	text, err := p.Render(vars)
+	if err != nil {
+		return nil, "", err
+	}
+	return p, text, nil
+}
+
+// EstimateTokens 估算文本的 Token 数：中日韩字符按每字 1 个，其余按每 4 字节 1 个
+func EstimateTokens(text string) int {
+	wide, other := 0, 0
+	for _, r := range text {
+		if isWide(r) {
+			wide++
+		} else {
+			other += utf8.RuneLen(r)
+		}
+	}
+	return wide + (other+3)/4
+}
+
+// isWide 判断是否为按单字计数的中日韩字符或全角标点
+func isWide(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303f) || (r >= 0xff00 && r <= 0xffef)
+}
+
+// SplitTranscript 按句子将文本切分为不超过 maxTokens 的片段，相邻片段重叠约 overlap 个 Token
+func SplitTranscript(text string, maxTokens, overlap int) []string {
+	if overlap > maxTokens/2 {
+		overlap = maxTokens / 2
+	}
+
+	var chunks []string
+	var current []string
+	currentTokens := 0
+	fresh := false // current 中是否有上一段之外的新句子
+	for _, sentence := range splitSentences(text, maxTokens-overlap) {
+		tokens := EstimateTokens(sentence)
+		if fresh && currentTokens+tokens > maxTokens {
+			chunks = append(chunks, strings.Join(current, ""))
+
+			// 保留末尾若干句作为下一段的开头
+			kept, keptTokens := 0, 0
+			for i := len(current) - 1; i > 0; i-- {
+				t := EstimateTokens(current[i])
+				if keptTokens+t > overlap {
+					break
+				}
+				keptTokens += t
+				kept++
+			}
+			current = append([]string(nil), current[len(current)-kept:]...)
+			currentTokens = keptTokens
+		}
+		current = append(current, sentence)
+		currentTokens += tokens
+		fresh = true
+	}
+	if fresh {
+		chunks = append(chunks, strings.Join(current, ""))
+	}
+	return chunks
+}
+
+// splitSentences 按句末标点切分文本，超过 maxTokens 的句子按字符硬切分
+func splitSentences(text string, maxTokens int) []string {
+	var sentences []string
+	var builder strings.Builder
+	wide, other := 0, 0
+	for _, r := range text {
+		builder.WriteRune(r)
+		if isWide(r) {
+			wide++
+		} else {
+			other += utf8.RuneLen(r)
+		}
+		if strings.ContainsRune("。！？；!?;\n", r) || wide+(other+3)/4 >= maxTokens {
+			sentences = append(sentences, builder.String())
+			builder.Reset()
+			wide, other = 0, 0
+		}
+	}
+	if builder.Len() > 0 {
+		sentences = append(sentences, builder.String())
+	}
+	return sentences
+}
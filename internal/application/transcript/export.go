@@ -0,0 +1,151 @@
+package transcript
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"iwut-smartclass-backend/internal/domain/transcript"
+)
+
+// 导出格式
+const (
+	FormatSRT  = "srt"
+	FormatVTT  = "vtt"
+	FormatText = "txt"
+	FormatJSON = "json"
+)
+
+// 没有片段时按此长度将全文合并为段落
+const paragraphRunes = 300
+
+// 片段缺少有效结束时间时的显示时长
+const minCueDuration = time.Second
+
+// WebVTT 字幕文本中需要转义的字符
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// RenderSRT 生成 SRT 字幕
+func RenderSRT(segments []*transcript.Segment) string {
+	labels := hasMultipleSpeakers(segments)
+	var b strings.Builder
+	for i, segment := range segments {
+		start, end := cueTiming(segment)
+		fmt.Fprintf(&b, "%d\n%s --> %s\n", i+1, formatTimestamp(start, ","), formatTimestamp(end, ","))
+		if labels {
+			b.WriteString(speakerLabel(segment.Speaker) + "：")
+		}
+		b.WriteString(strings.ReplaceAll(singleLine(segment.Text), "-->", "->"))
+		b.WriteString("\n\n")
+	}
+	return b.String()
+}
+
+// RenderVTT 生成 WebVTT 字幕，多个说话人时使用 <v> 标签标注
+func RenderVTT(segments []*transcript.Segment) string {
+	labels := hasMultipleSpeakers(segments)
+	var b strings.Builder
+	b.WriteString("WEBVTT\n\n")
+	for i, segment := range segments {
+		start, end := cueTiming(segment)
+		fmt.Fprintf(&b, "%d\n%s --> %s\n", i+1, formatTimestamp(start, "."), formatTimestamp(end, "."))
+		text := vttEscaper.Replace(singleLine(segment.Text))
+		if labels {
+			text = fmt.Sprintf("<v %s>%s", speakerLabel(segment.Speaker), text)
+		}
+		b.WriteString(text)
+		b.WriteString("\n\n")
+	}
+	return b.String()
+}
+
+// RenderText 生成纯文本，按说话人轮次分段并标注开始时间
+func RenderText(segments []*transcript.Segment) string {
+	labels := hasMultipleSpeakers(segments)
+	var paragraphs []string
+	var current strings.Builder
+	for i, segment := range segments {
+		if i == 0 || segment.Speaker != segments[i-1].Speaker {
+			if current.Len() > 0 {
+				paragraphs = append(paragraphs, current.String())
+				current.Reset()
+			}
+			fmt.Fprintf(&current, "[%s] ", formatClock(segment.Start))
+			if labels {
+				current.WriteString(speakerLabel(segment.Speaker) + "：")
+			}
+		}
+		current.WriteString(singleLine(segment.Text))
+	}
+	if current.Len() > 0 {
+		paragraphs = append(paragraphs, current.String())
+	}
+	return strings.Join(paragraphs, "\n\n") + "\n"
+}
+
+// RenderParagraphs 将没有时间信息的全文按句子合并为段落，用于只保存了 course.asr 的旧数据
+func RenderParagraphs(text string) string {
+	var paragraphs []string
+	var current strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		current.WriteString(line)
+		if utf8.RuneCountInString(current.String()) >= paragraphRunes {
+			paragraphs = append(paragraphs, current.String())
+			current.Reset()
+		}
+	}
+	if current.Len() > 0 {
+		paragraphs = append(paragraphs, current.String())
+	}
+	if len(paragraphs) == 0 {
+		return ""
+	}
+	return strings.Join(paragraphs, "\n\n") + "\n"
+}
+
+// cueTiming 返回字幕显示区间，保证结束时间晚于开始时间
+func cueTiming(segment *transcript.Segment) (time.Duration, time.Duration) {
+	start, end := segment.Start, segment.End
+	if start < 0 {
+		start = 0
+	}
+	if end <= start {
+		end = start + minCueDuration
+	}
+	return start, end
+}
+
+// formatTimestamp 格式化为 HH:MM:SS,mmm（SRT）或 HH:MM:SS.mmm（WebVTT）
+func formatTimestamp(d time.Duration, sep string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// formatClock 格式化为 HH:MM:SS
+func formatClock(d time.Duration) string {
+	s := int64(d / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", s/3600, s/60%60, s%60)
+}
+
+func speakerLabel(speaker int) string {
+	return fmt.Sprintf("说话人%d", speaker+1)
+}
+
+func hasMultipleSpeakers(segments []*transcript.Segment) bool {
+	for _, segment := range segments {
+		if segment.Speaker != segments[0].Speaker {
+			return true
+		}
+	}
+	return false
+}
+
+// singleLine 字幕文本中的空行会提前结束字幕块，合并为一行
+func singleLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
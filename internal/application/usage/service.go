@@ -0,0 +1,164 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"iwut-smartclass-backend/internal/domain/errors"
+	"iwut-smartclass-backend/internal/domain/usage"
+	"iwut-smartclass-backend/internal/domain/user"
+	"iwut-smartclass-backend/internal/infrastructure/logger"
+)
+
+// Service 用量应用服务，记录大模型与语音识别用量并检查额度
+type Service struct {
+	repo   usage.Repository
+	logger logger.Logger
+}
+
+// NewService 创建用量应用服务
+func NewService(repo usage.Repository, logger logger.Logger) *Service {
+	return &Service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// RecordLLM 记录一次大模型调用的 Token 用量
+func (s *Service) RecordLLM(ctx context.Context, u *user.User, model string, promptTokens, completionTokens int64) error {
+	if promptTokens == 0 && completionTokens == 0 {
+		return nil
+	}
+	return s.record(ctx, &usage.Entry{
+		Account:          u.Account,
+		TenantID:         u.TenantID,
+		Day:              time.Now().Format(usage.DayLayout),
+		Model:            model,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+	})
+}
+
+// RecordASR 记录一次语音识别的音频时长，不足一秒按一秒计
+func (s *Service) RecordASR(ctx context.Context, u *user.User, engine string, duration time.Duration) error {
+	if duration <= 0 {
+		return nil
+	}
+	return s.record(ctx, &usage.Entry{
+		Account:    u.Account,
+		TenantID:   u.TenantID,
+		Day:        time.Now().Format(usage.DayLayout),
+		Model:      "asr/" + engine,
+		AsrSeconds: int64(math.Ceil(duration.Seconds())),
+	})
+}
+
+func (s *Service) record(ctx context.Context, entry *usage.Entry) error {
+	if entry.Account == "" {
+		return nil
+	}
+	if err := s.repo.Record(ctx, entry); err != nil {
+		return errors.WrapError(err, "failed to record usage")
+	}
+	return nil
+}
+
+// QuotaStatus 额度及其当前周期的用量
+type QuotaStatus struct {
+	Quota   *usage.Quota
+	Used    usage.Totals
+	ResetAt time.Time // 下一周期开始时间
+}
+
+// Exceeded 当前周期的额度是否已用完
+func (q *QuotaStatus) Exceeded() bool {
+	return q.Quota.Exceeded(q.Used)
+}
+
+// Quotas 返回适用于用户的额度（账号与所属租户）及各自当前周期的用量
+func (s *Service) Quotas(ctx context.Context, u *user.User, now time.Time) ([]*QuotaStatus, error) {
+	quotas, err := s.repo.FindQuotas(ctx, usage.ScopeUser, u.Account)
+	if err != nil {
+		return nil, errors.WrapError(err, "failed to find usage quotas")
+	}
+	if u.TenantID != 0 {
+		tenantQuotas, err := s.repo.FindQuotas(ctx, usage.ScopeTenant, strconv.Itoa(u.TenantID))
+		if err != nil {
+			return nil, errors.WrapError(err, "failed to find usage quotas")
+		}
+		quotas = append(quotas, tenantQuotas...)
+	}
+
+	statuses := make([]*QuotaStatus, 0, len(quotas))
+	for _, quota := range quotas {
+		start, end := usage.PeriodRange(quota.Period, now)
+		fromDay, toDay := start.Format(usage.DayLayout), now.Format(usage.DayLayout)
+
+		var used usage.Totals
+		if quota.Scope == usage.ScopeTenant {
+			used, err = s.repo.SumByTenant(ctx, u.TenantID, fromDay, toDay)
+		} else {
+			used, err = s.repo.SumByAccount(ctx, u.Account, fromDay, toDay)
+		}
+		if err != nil {
+			return nil, errors.WrapError(err, "failed to sum usage")
+		}
+		statuses = append(statuses, &QuotaStatus{Quota: quota, Used: used, ResetAt: end})
+	}
+	return statuses, nil
+}
+
+// CheckQuota 用户或所属租户的任一额度已用完时返回请求过多错误
+func (s *Service) CheckQuota(ctx context.Context, u *user.User, now time.Time) error {
+	statuses, err := s.Quotas(ctx, u, now)
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		if status.Exceeded() {
+			s.logger.Warn("usage quota exceeded",
+				logger.String("account", u.Account),
+				logger.String("scope", status.Quota.Scope),
+				logger.String("period", status.Quota.Period),
+			)
+			message := fmt.Sprintf("%s %s quota exceeded", status.Quota.Scope, status.Quota.Period)
+			return errors.NewQuotaExceededError(message, status.ResetAt.Sub(now))
+		}
+	}
+	return nil
+}
+
+// Report 用户用量报告
+type Report struct {
+	Day     usage.Totals
+	Month   usage.Totals
+	Entries []*usage.Entry // 本月台账，按日期倒序
+	Quotas  []*QuotaStatus
+}
+
+// Report 返回用户今日与本月的用量、本月台账与适用的额度
+func (s *Service) Report(ctx context.Context, u *user.User, now time.Time) (*Report, error) {
+	monthStart, _ := usage.PeriodRange(usage.PeriodMonth, now)
+	today := now.Format(usage.DayLayout)
+
+	entries, err := s.repo.ListByAccount(ctx, u.Account, monthStart.Format(usage.DayLayout), today)
+	if err != nil {
+		return nil, errors.WrapError(err, "failed to list usage")
+	}
+	quotas, err := s.Quotas(ctx, u, now)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Entries: entries, Quotas: quotas}
+	for _, entry := range entries {
+		report.Month.Add(entry)
+		if entry.Day == today {
+			report.Day.Add(entry)
+		}
+	}
+	return report, nil
+}
@@ -5,4 +5,12 @@ import "iwut-smartclass-backend/internal/database/struct"
 var Structs = []interface{}{
 	&_struct.Course{},
 	&_struct.Summary{},
+	&_struct.QueueJob{},
+	&_struct.QueueDeadJob{},
+	&_struct.QueueLock{},
+	&_struct.PromptTemplate{},
+	&_struct.TranscriptSegment{},
+	&_struct.RateLimitBucket{},
+	&_struct.UsageLedger{},
+	&_struct.UsageQuota{},
 }
@@ -1,20 +1,23 @@
 package _struct
 
 type Course struct {
-	SubID         int    `gorm:"primaryKey;column:sub_id"`
-	CourseID      int    `gorm:"column:course_id"`
-	Name          string `gorm:"column:name"`
-	Teacher       string `gorm:"column:teacher"`
-	Location      string `gorm:"column:location"`
-	Date          string `gorm:"column:date"`
-	Time          string `gorm:"column:time"`
-	Video         string `gorm:"column:video"`
-	Asr           string `gorm:"column:asr;type:longtext"`
-	SummaryStatus string `gorm:"column:summary_status"`
-	SummaryData   string `gorm:"column:summary_data;type:longtext"`
-	Model         string `gorm:"column:model"`
-	Token         uint32 `gorm:"column:token"`
-	SummaryUser   string `gorm:"column:summary_user"`
+	SubID           int    `gorm:"primaryKey;column:sub_id"`
+	CourseID        int    `gorm:"column:course_id"`
+	Name            string `gorm:"column:name"`
+	Teacher         string `gorm:"column:teacher"`
+	Location        string `gorm:"column:location"`
+	Date            string `gorm:"column:date"`
+	Time            string `gorm:"column:time"`
+	Video           string `gorm:"column:video"`
+	Asr             string `gorm:"column:asr;type:longtext"`
+	SummaryStatus   string `gorm:"column:summary_status"`
+	SummaryStatusAt int64  `gorm:"column:summary_status_at"`
+	SummaryData     string `gorm:"column:summary_data;type:longtext"`
+	SummaryPartial  string `gorm:"column:summary_partial;type:longtext"`
+	Model           string `gorm:"column:model"`
+	PromptVersion   string `gorm:"column:prompt_version"`
+	Token           uint32 `gorm:"column:token"`
+	SummaryUser     string `gorm:"column:summary_user"`
 }
 
 func (Course) TableName() string {
@@ -0,0 +1,17 @@
+package _struct
+
+// PromptTemplate 提示词模板，同一用途与作用域下按版本保存历史
+type PromptTemplate struct {
+	ID         int    `gorm:"primaryKey;autoIncrement;column:id"`
+	Kind       string `gorm:"column:kind;size:32;uniqueIndex:idx_prompt_version"`
+	Scope      string `gorm:"column:scope;size:32;uniqueIndex:idx_prompt_version"`
+	ScopeValue string `gorm:"column:scope_value;size:191;uniqueIndex:idx_prompt_version"`
+	Version    int    `gorm:"column:version;uniqueIndex:idx_prompt_version"`
+	Content    string `gorm:"column:content;type:longtext"`
+	Enabled    bool   `gorm:"column:enabled"`
+	CreatedAt  int64  `gorm:"column:created_at"`
+}
+
+func (PromptTemplate) TableName() string {
+	return "prompt_template"
+}
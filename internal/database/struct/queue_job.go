@@ -0,0 +1,50 @@
+package _struct
+
+// QueueJob 队列任务，时间字段为毫秒时间戳
+type QueueJob struct {
+	Queue       string `gorm:"primaryKey;column:queue;size:64"`
+	ID          string `gorm:"primaryKey;column:id;size:191"`
+	Type        string `gorm:"column:type;size:64"`
+	Data        string `gorm:"column:data;type:longtext"`
+	Labels      string `gorm:"column:labels;type:text"`
+	Priority    int    `gorm:"column:priority"`
+	Owner       string `gorm:"column:owner;size:128;index"`
+	Attempts    string `gorm:"column:attempts;type:text"`
+	State       string `gorm:"column:state;size:16;index"`
+	EnqueuedAt  int64  `gorm:"column:enqueued_at"`
+	VirtualAt   int64  `gorm:"column:virtual_at"`
+	AvailableAt int64  `gorm:"column:available_at;index"`
+	LeaseOwner  string `gorm:"column:lease_owner;size:128"`
+	LeaseToken  string `gorm:"column:lease_token;size:32"`
+	LeaseUntil  int64  `gorm:"column:lease_until"`
+	Requeues    int    `gorm:"column:requeues"`
+}
+
+func (QueueJob) TableName() string {
+	return "queue_job"
+}
+
+// QueueDeadJob 死信任务
+type QueueDeadJob struct {
+	Queue    string `gorm:"primaryKey;column:queue;size:64"`
+	ID       string `gorm:"primaryKey;column:id;size:191"`
+	Type     string `gorm:"column:type;size:64"`
+	Data     string `gorm:"column:data;type:longtext"`
+	Labels   string `gorm:"column:labels;type:text"`
+	Attempts string `gorm:"column:attempts;type:text"`
+	DeadAt   int64  `gorm:"column:dead_at"`
+	Requeues int    `gorm:"column:requeues"`
+}
+
+func (QueueDeadJob) TableName() string {
+	return "queue_dead_job"
+}
+
+// QueueLock 队列锁，入队时锁定对应队列的行，使容量检查与写入串行执行
+type QueueLock struct {
+	Queue string `gorm:"primaryKey;column:queue;size:64"`
+}
+
+func (QueueLock) TableName() string {
+	return "queue_lock"
+}
@@ -0,0 +1,12 @@
+package _struct
+
+// RateLimitBucket 限流令牌桶，UpdatedAt 为毫秒时间戳，为 0 表示新建的桶
+type RateLimitBucket struct {
+	BucketKey string  `gorm:"primaryKey;column:bucket_key;size:191"`
+	Tokens    float64 `gorm:"column:tokens"`
+	UpdatedAt int64   `gorm:"column:updated_at;index"`
+}
+
+func (RateLimitBucket) TableName() string {
+	return "rate_limit_bucket"
+}
@@ -0,0 +1,16 @@
+package _struct
+
+// TranscriptSegment 转写片段，时间字段为毫秒
+type TranscriptSegment struct {
+	ID      int64  `gorm:"primaryKey;autoIncrement;column:id"`
+	SubID   int    `gorm:"column:sub_id;index:idx_transcript_sub_seq"`
+	Seq     int    `gorm:"column:seq;index:idx_transcript_sub_seq"`
+	StartMs int64  `gorm:"column:start_ms"`
+	EndMs   int64  `gorm:"column:end_ms"`
+	Speaker int    `gorm:"column:speaker"`
+	Text    string `gorm:"column:text;type:text"`
+}
+
+func (TranscriptSegment) TableName() string {
+	return "transcript_segment"
+}
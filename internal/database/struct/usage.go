@@ -0,0 +1,30 @@
+package _struct
+
+// UsageLedger 用量台账，同一账号、日期与模型合并为一行
+type UsageLedger struct {
+	Account          string `gorm:"primaryKey;column:account;size:128"`
+	Day              string `gorm:"primaryKey;column:day;size:10;index:idx_usage_tenant_day,priority:2"`
+	Model            string `gorm:"primaryKey;column:model;size:128"`
+	TenantID         int    `gorm:"column:tenant_id;index:idx_usage_tenant_day,priority:1"`
+	PromptTokens     int64  `gorm:"column:prompt_tokens"`
+	CompletionTokens int64  `gorm:"column:completion_tokens"`
+	AsrSeconds       int64  `gorm:"column:asr_seconds"`
+}
+
+func (UsageLedger) TableName() string {
+	return "usage_ledger"
+}
+
+// UsageQuota 用量额度，UpdatedAt 为秒级时间戳
+type UsageQuota struct {
+	Scope         string `gorm:"primaryKey;column:scope;size:16"`
+	ScopeValue    string `gorm:"primaryKey;column:scope_value;size:128"`
+	Period        string `gorm:"primaryKey;column:period;size:16"`
+	MaxTokens     int64  `gorm:"column:max_tokens"`
+	MaxAsrSeconds int64  `gorm:"column:max_asr_seconds"`
+	UpdatedAt     int64  `gorm:"column:updated_at"`
+}
+
+func (UsageQuota) TableName() string {
+	return "usage_quota"
+}
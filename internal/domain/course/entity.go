@@ -1,21 +1,26 @@
 package course
 
+import "time"
+
 // Course 课程实体
 type Course struct {
-	SubID         int
-	CourseID      int
-	Name          string
-	Teacher       string
-	Location      string
-	Date          string
-	Time          string
-	Video         string
-	Asr           string
-	SummaryStatus string
-	SummaryData   string
-	Model         string
-	Token         uint32
-	SummaryUser   string
+	SubID           int
+	CourseID        int
+	Name            string
+	Teacher         string
+	Location        string
+	Date            string
+	Time            string
+	Video           string
+	Asr             string
+	SummaryStatus   string
+	SummaryStatusAt time.Time
+	SummaryData     string
+	SummaryPartial  string // 生成过程中保存的部分摘要，成功后清空
+	Model           string
+	PromptVersion   string
+	Token           uint32
+	SummaryUser     string
 }
 
 // HasVideo 检查是否有视频
@@ -1,6 +1,9 @@
 package course
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // Repository 课程仓储接口
 type Repository interface {
@@ -14,6 +17,10 @@ type Repository interface {
 	UpdateAsr(ctx context.Context, subID int, asr string) error
 	// UpdateSummaryStatus 更新摘要状态
 	UpdateSummaryStatus(ctx context.Context, subID int, status string) error
-	// UpdateSummary 更新摘要数据
-	UpdateSummary(ctx context.Context, subID int, summary, model string, token uint32, user string) error
+	// FindStaleGenerating 查找在 before 之前进入生成中状态的课程
+	FindStaleGenerating(ctx context.Context, before time.Time) ([]*Course, error)
+	// UpdateSummaryPartial 保存生成过程中的部分摘要，不影响已完成的摘要
+	UpdateSummaryPartial(ctx context.Context, subID int, partial string) error
+	// UpdateSummary 更新摘要数据并清空部分摘要
+	UpdateSummary(ctx context.Context, subID int, summary, model, promptVersion string, token uint32, user string) error
 }
@@ -3,6 +3,7 @@ package errors
 import (
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // ErrorType 错误类型
@@ -15,14 +16,18 @@ const (
 	ErrorTypeForbidden    ErrorType = "forbidden"    // 禁止访问
 	ErrorTypeInternal     ErrorType = "internal"     // 内部错误
 	ErrorTypeExternal     ErrorType = "external"     // 外部服务错误
+	ErrorTypeTooMany      ErrorType = "too_many"     // 请求过多
+	ErrorTypeQueueFull    ErrorType = "queue_full"   // 队列已满
+	ErrorTypeUnavailable  ErrorType = "unavailable"  // 服务暂不可用
 )
 
 // DomainError 领域错误
 type DomainError struct {
-	Type    ErrorType
-	Code    int
-	Message string
-	Err     error
+	Type       ErrorType
+	Code       int
+	Message    string
+	Err        error
+	RetryAfter time.Duration // 建议客户端重试的等待时间，为 0 时不返回
 }
 
 func (e *DomainError) Error() string {
@@ -54,6 +59,10 @@ func (e *DomainError) HTTPStatus() int {
 		return http.StatusInternalServerError
 	case ErrorTypeExternal:
 		return http.StatusBadGateway
+	case ErrorTypeTooMany:
+		return http.StatusTooManyRequests
+	case ErrorTypeQueueFull, ErrorTypeUnavailable:
+		return http.StatusServiceUnavailable
 	default:
 		return http.StatusInternalServerError
 	}
@@ -116,6 +125,55 @@ func NewExternalError(service string, err error) *DomainError {
 	}
 }
 
+// NewTooManyRequestsError 创建请求过多错误
+func NewTooManyRequestsError(message string) *DomainError {
+	return &DomainError{
+		Type:    ErrorTypeTooMany,
+		Code:    http.StatusTooManyRequests,
+		Message: message,
+	}
+}
+
+// NewRateLimitedError 创建触发限流的请求过多错误
+func NewRateLimitedError(scope string, retryAfter time.Duration) *DomainError {
+	return &DomainError{
+		Type:       ErrorTypeTooMany,
+		Code:       http.StatusTooManyRequests,
+		Message:    fmt.Sprintf("%s rate limit exceeded, please retry later", scope),
+		RetryAfter: retryAfter,
+	}
+}
+
+// NewQuotaExceededError 创建额度用完的请求过多错误，retryAfter 为到下一周期的时间
+func NewQuotaExceededError(message string, retryAfter time.Duration) *DomainError {
+	return &DomainError{
+		Type:       ErrorTypeTooMany,
+		Code:       http.StatusTooManyRequests,
+		Message:    message,
+		RetryAfter: retryAfter,
+	}
+}
+
+// NewQueueFullError 创建队列已满错误
+func NewQueueFullError(queue string, retryAfter time.Duration) *DomainError {
+	return &DomainError{
+		Type:       ErrorTypeQueueFull,
+		Code:       http.StatusServiceUnavailable,
+		Message:    fmt.Sprintf("%s is full, please retry later", queue),
+		RetryAfter: retryAfter,
+	}
+}
+
+// NewServiceUnavailableError 创建服务暂不可用错误
+func NewServiceUnavailableError(message string, err error) *DomainError {
+	return &DomainError{
+		Type:    ErrorTypeUnavailable,
+		Code:    http.StatusServiceUnavailable,
+		Message: message,
+		Err:     err,
+	}
+}
+
 // WrapError 包装错误
 func WrapError(err error, message string) *DomainError {
 	if domainErr, ok := err.(*DomainError); ok {
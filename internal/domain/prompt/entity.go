@@ -0,0 +1,57 @@
+package prompt
+
+import (
+	"fmt"
+	"time"
+)
+
+// 模板用途
+const (
+	KindSummary = "summary" // 单次生成摘要
+	KindSection = "section" // 长文本分段总结
+	KindReduce  = "reduce"  // 合并分段要点
+)
+
+// 模板作用域，按此顺序从具体到通用匹配
+const (
+	ScopeCourseID   = "course_id"
+	ScopeCourseName = "course_name"
+	ScopeTeacher    = "teacher"
+	ScopeTenant     = "tenant"
+	ScopeDefault    = "default"
+	ScopeEmbedded   = "embedded" // 内置模板，不保存在数据库中
+)
+
+// Template 提示词模板
+type Template struct {
+	ID         int
+	Kind       string
+	Scope      string
+	ScopeValue string
+	Version    int
+	Content    string
+	Enabled    bool
+	CreatedAt  time.Time
+}
+
+// Ref 返回模板版本标识，如 summary/course_id:123@v2
+func (t *Template) Ref() string {
+	if t.ScopeValue == "" {
+		return fmt.Sprintf("%s/%s@v%d", t.Kind, t.Scope, t.Version)
+	}
+	return fmt.Sprintf("%s/%s:%s@v%d", t.Kind, t.Scope, t.ScopeValue, t.Version)
+}
+
+// IsValidKind 检查模板用途是否合法
+func IsValidKind(kind string) bool {
+	return kind == KindSummary || kind == KindSection || kind == KindReduce
+}
+
+// IsValidScope 检查作用域是否可以保存到数据库
+func IsValidScope(scope string) bool {
+	switch scope {
+	case ScopeCourseID, ScopeCourseName, ScopeTeacher, ScopeTenant, ScopeDefault:
+		return true
+	}
+	return false
+}
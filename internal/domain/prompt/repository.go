@@ -0,0 +1,17 @@
+package prompt
+
+import "context"
+
+// Repository 提示词模板仓储接口
+type Repository interface {
+	// FindLatest 查找作用域下启用的最新版本模板，不存在时返回 nil
+	FindLatest(ctx context.Context, kind, scope, scopeValue string) (*Template, error)
+	// FindByID 根据ID查找模板
+	FindByID(ctx context.Context, id int) (*Template, error)
+	// List 列出模板，kind 为空时列出全部
+	List(ctx context.Context, kind string) ([]*Template, error)
+	// Create 保存新版本模板，版本号在同一作用域内递增
+	Create(ctx context.Context, template *Template) error
+	// SetEnabled 启用或停用模板
+	SetEnabled(ctx context.Context, id int, enabled bool) error
+}
@@ -8,7 +8,9 @@ type Summary struct {
 	SubID   int
 	CreateAt time.Time
 	Summary string
+	Partial string // 生成过程中保存的部分摘要，成功后清空
 	Model   string
+	PromptVersion string
 	Token   uint32
 }
 
@@ -0,0 +1,13 @@
+package transcript
+
+import "time"
+
+// Segment 带时间戳与说话人的转写片段
+type Segment struct {
+	SubID   int
+	Index   int
+	Start   time.Duration
+	End     time.Duration
+	Speaker int
+	Text    string
+}
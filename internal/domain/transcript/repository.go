@@ -0,0 +1,11 @@
+package transcript
+
+import "context"
+
+// Repository 转写片段仓储接口
+type Repository interface {
+	// FindBySubID 按时间顺序查找课程的转写片段
+	FindBySubID(ctx context.Context, subID int) ([]*Segment, error)
+	// Replace 替换课程的全部转写片段
+	Replace(ctx context.Context, subID int, segments []*Segment) error
+}
@@ -0,0 +1,85 @@
+package usage
+
+import "time"
+
+// 额度作用域
+const (
+	ScopeUser   = "user"   // 按账号
+	ScopeTenant = "tenant" // 按租户（学校）
+)
+
+// 额度周期，按服务器本地时间划分
+const (
+	PeriodDay   = "day"
+	PeriodMonth = "month"
+)
+
+// DayLayout 台账日期格式
+const DayLayout = "2006-01-02"
+
+// Entry 台账条目，记录账号某天在某个模型上的用量
+type Entry struct {
+	Account          string
+	TenantID         int
+	Day              string // 本地日期，如 2025-03-26
+	Model            string // 大模型为服务与模型，如 deepseek/deepseek-chat；语音识别为 asr/<引擎>
+	PromptTokens     int64
+	CompletionTokens int64
+	AsrSeconds       int64
+}
+
+// Totals 用量合计
+type Totals struct {
+	PromptTokens     int64
+	CompletionTokens int64
+	AsrSeconds       int64
+}
+
+// Tokens 返回 Token 总数
+func (t Totals) Tokens() int64 {
+	return t.PromptTokens + t.CompletionTokens
+}
+
+// Add 累加台账条目
+func (t *Totals) Add(e *Entry) {
+	t.PromptTokens += e.PromptTokens
+	t.CompletionTokens += e.CompletionTokens
+	t.AsrSeconds += e.AsrSeconds
+}
+
+// Quota 额度，上限为 0 表示不限制该项
+type Quota struct {
+	Scope         string
+	ScopeValue    string // 账号或租户ID
+	Period        string
+	MaxTokens     int64
+	MaxAsrSeconds int64
+	UpdatedAt     time.Time
+}
+
+// Exceeded 判断用量是否已达到额度
+func (q *Quota) Exceeded(used Totals) bool {
+	return (q.MaxTokens > 0 && used.Tokens() >= q.MaxTokens) ||
+		(q.MaxAsrSeconds > 0 && used.AsrSeconds >= q.MaxAsrSeconds)
+}
+
+// IsValidScope 检查额度作用域是否合法
+func IsValidScope(scope string) bool {
+	return scope == ScopeUser || scope == ScopeTenant
+}
+
+// IsValidPeriod 检查额度周期是否合法
+func IsValidPeriod(period string) bool {
+	return period == PeriodDay || period == PeriodMonth
+}
+
+// PeriodRange 返回 now 所在周期的开始时间与下一周期的开始时间
+func PeriodRange(period string, now time.Time) (time.Time, time.Time) {
+	year, month, day := now.Date()
+	if period == PeriodMonth {
+		start := time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 1, 0)
+	}
+	start := time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	return start, start.AddDate(0, 0, 1)
+}
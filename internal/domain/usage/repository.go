@@ -0,0 +1,23 @@
+package usage
+
+import "context"
+
+// Repository 用量台账与额度仓储接口
+type Repository interface {
+	// Record 累加台账条目，同一账号、日期与模型合并为一行
+	Record(ctx context.Context, entry *Entry) error
+	// ListByAccount 列出账号在 [fromDay, toDay] 内的台账条目
+	ListByAccount(ctx context.Context, account, fromDay, toDay string) ([]*Entry, error)
+	// SumByAccount 合计账号在 [fromDay, toDay] 内的用量
+	SumByAccount(ctx context.Context, account, fromDay, toDay string) (Totals, error)
+	// SumByTenant 合计租户在 [fromDay, toDay] 内的用量
+	SumByTenant(ctx context.Context, tenantID int, fromDay, toDay string) (Totals, error)
+	// ListQuotas 列出额度，scope 为空时列出全部
+	ListQuotas(ctx context.Context, scope string) ([]*Quota, error)
+	// FindQuotas 查找作用域下各周期的额度
+	FindQuotas(ctx context.Context, scope, scopeValue string) ([]*Quota, error)
+	// SaveQuota 创建或更新额度
+	SaveQuota(ctx context.Context, quota *Quota) error
+	// DeleteQuota 删除额度
+	DeleteQuota(ctx context.Context, scope, scopeValue, period string) error
+}
@@ -0,0 +1,30 @@
+package user
+
+import "context"
+
+type contextKey struct{}
+
+type identity struct {
+	user  *User
+	token string
+}
+
+// NewContext 返回携带已认证用户及其令牌的 ctx
+func NewContext(ctx context.Context, u *User, token string) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity{user: u, token: token})
+}
+
+// FromContext 读取 ctx 中的已认证用户
+func FromContext(ctx context.Context) (*User, bool) {
+	id, ok := ctx.Value(contextKey{}).(identity)
+	if !ok || id.user == nil {
+		return nil, false
+	}
+	return id.user, true
+}
+
+// TokenFromContext 读取 ctx 中已认证用户的令牌
+func TokenFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(identity)
+	return id.token
+}
@@ -1,7 +1,9 @@
 package user
 
+import "context"
+
 // ExternalService 外部用户服务接口
 type ExternalService interface {
 	// GetUserInfo 获取用户信息
-	GetUserInfo(token string) (*User, error)
+	GetUserInfo(ctx context.Context, token string) (*User, error)
 }
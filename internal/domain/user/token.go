@@ -0,0 +1,37 @@
+package user
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// TokenExpiry 读取 JWT 令牌的 exp 声明，不校验签名；不是 JWT 或没有 exp 时返回 false
+func TokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp json.Number `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == "" {
+		return time.Time{}, false
+	}
+	exp, err := claims.Exp.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(exp), 0), true
+}
+
+// TokenExpired 令牌是否已过期，无法判断时视为未过期
+func TokenExpired(token string, now time.Time) bool {
+	exp, ok := TokenExpiry(token)
+	return ok && !now.Before(exp)
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU 带过期时间的最近最少使用缓存，并发安全
+type LRU[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	items    map[K]*list.Element
+	order    *list.List // 队首为最近使用
+}
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// NewLRU 创建缓存，capacity 为最大条目数，ttl 为条目的有效期
+func NewLRU[K comparable, V any](capacity int, ttl time.Duration) *LRU[K, V] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LRU[K, V]{
+		capacity: capacity,
+		ttl:      ttl,
+		items:    make(map[K]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get 读取未过期的条目
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	element, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	item := element.Value.(*entry[K, V])
+	if !time.Now().Before(item.expiresAt) {
+		c.remove(element)
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	return item.value, true
+}
+
+// Set 写入条目，超出容量时淘汰最久未使用的条目
+func (c *LRU[K, V]) Set(key K, value V) {
+	c.SetUntil(key, value, time.Time{})
+}
+
+// SetUntil 写入条目，deadline 早于缓存有效期时条目在 deadline 过期，为零值时使用缓存有效期
+func (c *LRU[K, V]) SetUntil(key K, value V, deadline time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if !deadline.IsZero() && deadline.Before(expiresAt) {
+		expiresAt = deadline
+	}
+	if element, ok := c.items[key]; ok {
+		item := element.Value.(*entry[K, V])
+		item.value = value
+		item.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+// Delete 删除条目
+func (c *LRU[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		c.remove(element)
+	}
+}
+
+// Len 返回条目数量，包括尚未清理的过期条目
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *LRU[K, V]) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.items, element.Value.(*entry[K, V]).key)
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"reflect"
 	"strconv"
@@ -11,43 +13,111 @@ import (
 
 // Config 应用配置
 type Config struct {
-	Debug                bool
-	Port                 string
-	Database             string
-	LogSave              bool
-	SummaryWorkerCount   int
-	SummaryQueueSize     int
-	TencentSecretId      []string
-	TencentSecretKey     []string
-	BucketUrl            string
-	OpenaiEndpoint       string
-	OpenaiKey            string
-	OpenaiModel          string
-	Temperature          float32
-	InfoSimple           string
-	GetWeekSchedules     string
-	SearchLiveCourseList string
+	Debug                  bool
+	Port                   string
+	Database               string
+	LogSave                bool
+	SummaryWorkerCount     int
+	SummaryQueueSize       int
+	SummaryMaxAttempts     int
+	SummaryRetryDelay      int
+	SummaryRetryMaxDelay   int
+	SummaryMaxUserJobs     int
+	SummaryEnqueueTimeout  int
+	SummaryStaleAfter      int
+	SummaryReaperInterval  int
+	SummaryReaperRequeue   bool
+	QueueBackend           string
+	JobTokenKey            string
+	ShutdownGracePeriod    int
+	TencentSecretId        []string
+	TencentSecretKey       []string
+	TencentKeyStrategy     string
+	TencentKeyCooldown     int
+	BucketUrl              string
+	AsrEngine              string
+	WhisperEndpoint        string
+	WhisperKey             string
+	WhisperModel           string
+	WhisperLanguage        string
+	OpenaiEndpoint         string
+	OpenaiKey              string
+	OpenaiModel            string
+	Temperature            float32
+	LlmProviders           []LLMProvider
+	OpenaiStream           bool
+	SummaryFlushInterval   int
+	SummaryChunkTokens     int
+	SummaryChunkOverlap    int
+	InfoSimple             string
+	UserCacheSize          int
+	UserCacheTtl           int
+	GetWeekSchedules       string
+	SearchLiveCourseList   string
+	VideoUrlTtl            int
+	AdminToken             string
+	RateLimitBackend       string
+	RateLimitUserPerHour   int
+	RateLimitUserBurst     int
+	RateLimitIpPerHour     int
+	RateLimitIpBurst       int
+	RateLimitGlobalPerHour int
+	RateLimitGlobalBurst   int
 }
 
 // DefaultConfig 返回默认配置
 func DefaultConfig() *Config {
 	return &Config{
-		Debug:                false,
-		Port:                 "8080",
-		Database:             "",
-		LogSave:              false,
-		SummaryWorkerCount:   2,
-		SummaryQueueSize:     20,
-		TencentSecretId:      []string{},
-		TencentSecretKey:     []string{},
-		BucketUrl:            "",
-		OpenaiEndpoint:       "",
-		OpenaiKey:            "",
-		OpenaiModel:          "",
-		Temperature:          0.3,
-		InfoSimple:           "",
-		GetWeekSchedules:     "",
-		SearchLiveCourseList: "",
+		Debug:                  false,
+		Port:                   "8080",
+		Database:               "",
+		LogSave:                false,
+		SummaryWorkerCount:     2,
+		SummaryQueueSize:       20,
+		SummaryMaxAttempts:     3,
+		SummaryRetryDelay:      30,
+		SummaryRetryMaxDelay:   600,
+		SummaryMaxUserJobs:     5,
+		SummaryEnqueueTimeout:  5,
+		SummaryStaleAfter:      7200,
+		SummaryReaperInterval:  600,
+		SummaryReaperRequeue:   false,
+		QueueBackend:           "file",
+		JobTokenKey:            "",
+		ShutdownGracePeriod:    60,
+		TencentSecretId:        []string{},
+		TencentSecretKey:       []string{},
+		TencentKeyStrategy:     "round_robin",
+		TencentKeyCooldown:     600,
+		BucketUrl:              "",
+		AsrEngine:              "tencent",
+		WhisperEndpoint:        "",
+		WhisperKey:             "",
+		WhisperModel:           "whisper-1",
+		WhisperLanguage:        "zh",
+		OpenaiEndpoint:         "",
+		OpenaiKey:              "",
+		OpenaiModel:            "",
+		Temperature:            0.3,
+		LlmProviders:           []LLMProvider{},
+		OpenaiStream:           true,
+		SummaryFlushInterval:   10,
+		SummaryChunkTokens:     12000,
+		SummaryChunkOverlap:    500,
+		InfoSimple:             "",
+		UserCacheSize:          1000,
+		UserCacheTtl:           300,
+		GetWeekSchedules:       "",
+		SearchLiveCourseList:   "",
+		VideoUrlTtl:            1800,
+		AdminToken:             "",
+		RateLimitBackend:       "memory",
+		RateLimitUserPerHour:   20,
+		RateLimitUserBurst:     5,
+		RateLimitIpPerHour:     60,
+		RateLimitIpBurst:       10,
+		RateLimitGlobalPerHour: 0,
+		RateLimitGlobalBurst:   0,
 	}
 }
 
@@ -97,6 +167,12 @@ func LoadConfigFromEnv(config *Config) error {
 				values[i] = strings.TrimSpace(v)
 			}
 			field.Set(reflect.ValueOf(values))
+		case "LlmProviders":
+			var providers []LLMProvider
+			if err := json.Unmarshal([]byte(envVal), &providers); err != nil {
+				return &ValidationError{Field: fieldName, Message: fmt.Sprintf("invalid JSON: %v", err)}
+			}
+			field.Set(reflect.ValueOf(providers))
 		case "Temperature":
 			if floatVal, err := strconv.ParseFloat(envVal, 32); err == nil {
 				field.SetFloat(floatVal)
@@ -123,21 +199,99 @@ func (c *Config) Validate() error {
 	if c.Database == "" {
 		return &ValidationError{Field: "Database", Message: "database connection string is required"}
 	}
-	if len(c.TencentSecretId) == 0 {
-		return &ValidationError{Field: "TencentSecretId", Message: "tencent secret id is required"}
+	if c.JobTokenKey != "" && len(c.JobTokenKey) < 16 {
+		return &ValidationError{Field: "JobTokenKey", Message: "job token key must be at least 16 characters"}
+	}
+	switch c.QueueBackend {
+	case "", "file", "mysql":
+	default:
+		return &ValidationError{Field: "QueueBackend", Message: "queue backend must be file or mysql"}
+	}
+	if len(c.TencentSecretId) != len(c.TencentSecretKey) {
+		return &ValidationError{Field: "TencentSecretKey", Message: fmt.Sprintf("got %d secret ids but %d secret keys", len(c.TencentSecretId), len(c.TencentSecretKey))}
+	}
+	if c.TencentKeyStrategy != "round_robin" && c.TencentKeyStrategy != "least_used" {
+		return &ValidationError{Field: "TencentKeyStrategy", Message: "tencent key strategy must be round_robin or least_used"}
+	}
+	if c.RateLimitBackend != "memory" && c.RateLimitBackend != "mysql" {
+		return &ValidationError{Field: "RateLimitBackend", Message: "rate limit backend must be memory or mysql"}
+	}
+	if c.RateLimitUserPerHour > 0 && c.RateLimitUserBurst < 1 {
+		return &ValidationError{Field: "RateLimitUserBurst", Message: "rate limit burst must be at least 1"}
 	}
-	if len(c.TencentSecretKey) == 0 {
-		return &ValidationError{Field: "TencentSecretKey", Message: "tencent secret key is required"}
+	if c.RateLimitIpPerHour > 0 && c.RateLimitIpBurst < 1 {
+		return &ValidationError{Field: "RateLimitIpBurst", Message: "rate limit burst must be at least 1"}
 	}
-	if c.OpenaiEndpoint == "" {
-		return &ValidationError{Field: "OpenaiEndpoint", Message: "openai endpoint is required"}
+	if c.RateLimitGlobalPerHour > 0 && c.RateLimitGlobalBurst < 1 {
+		return &ValidationError{Field: "RateLimitGlobalBurst", Message: "rate limit burst must be at least 1"}
 	}
-	if c.OpenaiKey == "" {
-		return &ValidationError{Field: "OpenaiKey", Message: "openai key is required"}
+	switch c.AsrEngine {
+	case "tencent":
+		if len(c.TencentSecretId) == 0 {
+			return &ValidationError{Field: "TencentSecretId", Message: "tencent secret id is required"}
+		}
+		if len(c.TencentSecretKey) == 0 {
+			return &ValidationError{Field: "TencentSecretKey", Message: "tencent secret key is required"}
+		}
+	case "whisper":
+		if c.WhisperEndpoint == "" {
+			return &ValidationError{Field: "WhisperEndpoint", Message: "whisper endpoint is required"}
+		}
+	default:
+		return &ValidationError{Field: "AsrEngine", Message: "asr engine must be tencent or whisper"}
+	}
+	if len(c.LlmProviders) == 0 {
+		if c.OpenaiEndpoint == "" {
+			return &ValidationError{Field: "OpenaiEndpoint", Message: "openai endpoint is required"}
+		}
+		if c.OpenaiKey == "" {
+			return &ValidationError{Field: "OpenaiKey", Message: "openai key is required"}
+		}
+	}
+	for i, provider := range c.LlmProviders {
+		if provider.Endpoint == "" || provider.Key == "" || provider.Model == "" {
+			return &ValidationError{Field: "LlmProviders", Message: fmt.Sprintf("provider %d requires endpoint, key and model", i)}
+		}
 	}
 	return nil
 }
 
+// LLMProvider 大模型服务配置，兼容 OpenAI Chat Completions 接口
+type LLMProvider struct {
+	Name        string   `json:"name"`
+	Endpoint    string   `json:"endpoint"`
+	Key         string   `json:"key"`
+	Model       string   `json:"model"`
+	Temperature *float32 `json:"temperature,omitempty"` // 为空时使用 TEMPERATURE
+	MaxTokens   int      `json:"max_tokens,omitempty"`  // 为 0 时不限制
+}
+
+// ResolveLLMProviders 返回按优先级排列的大模型服务，未配置 LLM_PROVIDERS 时使用 OPENAI_* 配置
+func (c *Config) ResolveLLMProviders() []LLMProvider {
+	providers := c.LlmProviders
+	if len(providers) == 0 {
+		providers = []LLMProvider{{
+			Name:     "openai",
+			Endpoint: c.OpenaiEndpoint,
+			Key:      c.OpenaiKey,
+			Model:    c.OpenaiModel,
+		}}
+	}
+
+	resolved := make([]LLMProvider, 0, len(providers))
+	for i, provider := range providers {
+		if provider.Name == "" {
+			provider.Name = fmt.Sprintf("provider%d", i+1)
+		}
+		if provider.Temperature == nil {
+			temperature := c.Temperature
+			provider.Temperature = &temperature
+		}
+		resolved = append(resolved, provider)
+	}
+	return resolved
+}
+
 // ValidationError 配置验证错误
 type ValidationError struct {
 	Field   string
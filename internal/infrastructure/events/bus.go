@@ -0,0 +1,122 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	subscriberBuffer = 64               // 每个订阅者的缓冲事件数
+	finalRetention   = 10 * time.Minute // 结束事件的保留时长
+)
+
+// Event 进程内事件
+type Event struct {
+	Type    string                 `json:"type"`
+	Message string                 `json:"message,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+	Final   bool                   `json:"final"` // 是否为结束事件，订阅方收到后可以关闭
+	// Transient 临时事件（如增量文本）不作为主题的最后一个事件保留
+	Transient bool `json:"-"`
+	// Audience 仅推送给该账号的订阅者，为空时推送给所有订阅者，由订阅方过滤
+	Audience string    `json:"-"`
+	Time     time.Time `json:"time"`
+}
+
+// VisibleTo 检查事件是否可以推送给账号
+func (e Event) VisibleTo(account string) bool {
+	return e.Audience == "" || e.Audience == account
+}
+
+// Bus 进程内事件总线，按主题发布事件并保留每个主题的最后一个事件
+type Bus struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan Event]struct{}
+	last        map[string]Event
+}
+
+var defaultBus = NewBus()
+
+// NewBus 创建事件总线
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[string]map[chan Event]struct{}),
+		last:        make(map[string]Event),
+	}
+}
+
+// GetBus 获取全局事件总线
+func GetBus() *Bus {
+	return defaultBus
+}
+
+// Publish 发布事件，订阅者处理过慢时丢弃其最早的未读事件
+func (b *Bus) Publish(topic string, event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.prune()
+	if !event.Transient {
+		b.last[topic] = event
+	}
+	for ch := range b.subscribers[topic] {
+		select {
+		case ch <- event:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- event:
+			default:
+			}
+		}
+	}
+}
+
+// Subscribe 订阅主题，已有事件时先收到最后一个事件，返回的函数用于取消订阅
+func (b *Bus) Subscribe(topic string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if event, ok := b.last[topic]; ok {
+		ch <- event
+	}
+	if b.subscribers[topic] == nil {
+		b.subscribers[topic] = make(map[chan Event]struct{})
+	}
+	b.subscribers[topic][ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers[topic], ch)
+		if len(b.subscribers[topic]) == 0 {
+			delete(b.subscribers, topic)
+		}
+	}
+}
+
+// Last 获取主题的最后一个事件
+func (b *Bus) Last(topic string) (Event, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	event, ok := b.last[topic]
+	return event, ok
+}
+
+// prune 清理过期的结束事件，调用方需持有 mu
+func (b *Bus) prune() {
+	for topic, event := range b.last {
+		if event.Final && time.Since(event.Time) > finalRetention {
+			delete(b.last, topic)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package external
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"iwut-smartclass-backend/internal/domain/errors"
+	"iwut-smartclass-backend/internal/domain/transcript"
+	"iwut-smartclass-backend/internal/infrastructure/config"
+	"iwut-smartclass-backend/internal/infrastructure/logger"
+)
+
+// ASR 引擎
+const (
+	ASREngineTencent = "tencent"
+	ASREngineWhisper = "whisper"
+)
+
+// ASRAudio 待识别的音频
+type ASRAudio struct {
+	Path string // 本地文件路径
+	URL  string // 公网地址，仅 RequiresURL 的引擎使用
+}
+
+// ASRResult 识别结果
+type ASRResult struct {
+	Text          string                // 去除时间戳后的全文
+	Segments      []*transcript.Segment // 带时间戳与说话人的片段，引擎不支持时为空
+	AudioDuration time.Duration         // 引擎返回的音频时长，未返回时为 0
+}
+
+// Duration 返回识别的音频时长，优先使用引擎返回的时长，否则以最后一个片段的结束时间计，都没有时为 0
+func (r ASRResult) Duration() time.Duration {
+	if r.AudioDuration > 0 {
+		return r.AudioDuration
+	}
+	if n := len(r.Segments); n > 0 {
+		return r.Segments[n-1].End
+	}
+	return 0
+}
+
+// ASRTask 已提交的异步识别任务，保存在检查点中以便恢复后继续查询
+type ASRTask struct {
+	ID       uint64
+	KeyIndex int // 创建任务所用的密钥序号，任务只能用同一密钥查询
+}
+
+// ASREngine 语音识别引擎
+type ASREngine interface {
+	// Name 返回引擎名称
+	Name() string
+	// RequiresURL 是否需要先将音频上传到 COS，以公网地址提交
+	RequiresURL() bool
+	// Transcribe 识别音频并返回文本。task 非空时继续查询已提交的任务；
+	// 异步引擎提交任务后调用 onSubmitted 以便保存检查点
+	Transcribe(ctx context.Context, audio ASRAudio, task *ASRTask, onSubmitted func(task ASRTask)) (ASRResult, error)
+}
+
+// NewASREngine 根据配置创建语音识别引擎
+func NewASREngine(cfg *config.Config, logger logger.Logger) (ASREngine, error) {
+	switch cfg.AsrEngine {
+	case "", ASREngineTencent:
+		pool, err := SharedCredentialPool(cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		return NewTencentASREngine(pool, logger), nil
+	case ASREngineWhisper:
+		return NewWhisperASREngine(cfg, logger), nil
+	default:
+		return nil, errors.NewInternalError("unknown ASR engine", fmt.Errorf("ASR_ENGINE=%q", cfg.AsrEngine))
+	}
+}
+
+// TencentASREngine 腾讯云录音文件识别，每个任务从密钥池中选取一组密钥
+type TencentASREngine struct {
+	pool   *CredentialPool
+	logger logger.Logger
+}
+
+// NewTencentASREngine 创建腾讯云语音识别引擎
+func NewTencentASREngine(pool *CredentialPool, logger logger.Logger) *TencentASREngine {
+	return &TencentASREngine{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+// Name 返回引擎名称
+func (e *TencentASREngine) Name() string {
+	return ASREngineTencent
+}
+
+// RequiresURL 腾讯云只能从公网地址拉取音频
+func (e *TencentASREngine) RequiresURL() bool {
+	return true
+}
+
+// Transcribe 创建识别任务并轮询结果
+func (e *TencentASREngine) Transcribe(ctx context.Context, audio ASRAudio, task *ASRTask, onSubmitted func(task ASRTask)) (ASRResult, error) {
+	if task == nil || task.ID == 0 {
+		submitted, err := e.createTask(ctx, audio.URL)
+		if err != nil {
+			return ASRResult{}, err
+		}
+		task = &submitted
+		if onSubmitted != nil {
+			onSubmitted(*task)
+		}
+	}
+
+	// 任务只能用创建它的密钥查询，即使该密钥正在冷却
+	credential, err := e.pool.Get(task.KeyIndex)
+	if err != nil {
+		return ASRResult{}, err
+	}
+	asrSvc, err := e.newService(credential)
+	if err != nil {
+		return ASRResult{}, err
+	}
+	result, err := asrSvc.WaitTask(ctx, task.ID)
+	if err != nil {
+		if ctx.Err() == nil {
+			e.pool.Report(task.KeyIndex, err)
+		}
+		return ASRResult{}, err
+	}
+	if duration := result.Duration(); duration > 0 {
+		e.pool.RecordAudio(task.KeyIndex, duration)
+	}
+	return result, nil
+}
+
+// createTask 依次尝试密钥池中的密钥创建任务，额度或鉴权错误时换下一组
+func (e *TencentASREngine) createTask(ctx context.Context, url string) (ASRTask, error) {
+	var lastErr error
+	for attempt := 0; attempt < e.pool.Len(); attempt++ {
+		keyIndex, credential, err := e.pool.Acquire()
+		if err != nil {
+			if lastErr != nil {
+				return ASRTask{}, lastErr
+			}
+			return ASRTask{}, err
+		}
+		asrSvc, err := e.newService(credential)
+		if err != nil {
+			return ASRTask{}, err
+		}
+		taskID, err := asrSvc.CreateTask(ctx, url)
+		if err == nil {
+			return ASRTask{ID: taskID, KeyIndex: keyIndex}, nil
+		}
+		if ctx.Err() != nil {
+			return ASRTask{}, err
+		}
+		e.pool.Report(keyIndex, err)
+		if _, ok := IsCredentialError(err); !ok {
+			return ASRTask{}, err
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		return ASRTask{}, errors.NewInternalError("no tencent credentials configured", fmt.Errorf("TENCENT_SECRET_ID is empty"))
+	}
+	return ASRTask{}, lastErr
+}
+
+func (e *TencentASREngine) newService(credential Credential) (*ASRService, error) {
+	asrSvc, err := NewASRService(credential.SecretID, credential.SecretKey, e.logger)
+	if err != nil {
+		e.logger.Error("failed to create ASR service", logger.String("error", err.Error()))
+		return nil, err
+	}
+	return asrSvc, nil
+}
+
+// WhisperASREngine 兼容 OpenAI /audio/transcriptions 接口的识别服务，如自建的 whisper 服务
+type WhisperASREngine struct {
+	endpoint string
+	key      string
+	model    string
+	language string
+	logger   logger.Logger
+}
+
+// NewWhisperASREngine 创建 Whisper 语音识别引擎
+func NewWhisperASREngine(cfg *config.Config, logger logger.Logger) *WhisperASREngine {
+	return &WhisperASREngine{
+		endpoint: cfg.WhisperEndpoint,
+		key:      cfg.WhisperKey,
+		model:    cfg.WhisperModel,
+		language: cfg.WhisperLanguage,
+		logger:   logger,
+	}
+}
+
+// Name 返回引擎名称
+func (e *WhisperASREngine) Name() string {
+	return ASREngineWhisper
+}
+
+// RequiresURL 直接上传本地文件，不经过 COS
+func (e *WhisperASREngine) RequiresURL() bool {
+	return false
+}
+
+// Transcribe 上传音频文件并同步返回识别结果
+func (e *WhisperASREngine) Transcribe(ctx context.Context, audio ASRAudio, task *ASRTask, onSubmitted func(task ASRTask)) (ASRResult, error) {
+	e.logger.Info("creating whisper transcription", logger.String("file", audio.Path))
+
+	body, contentType, err := e.newBody(audio.Path)
+	if err != nil {
+		return ASRResult{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, body)
+	if err != nil {
+		_ = body.Close()
+		return ASRResult{}, errors.NewInternalError("failed to create request", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if e.key != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", e.key))
+	}
+
+	// 识别耗时与音频长度相关，总时长由 ctx 控制
+	resp, err := (&http.Client{}).Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ASRResult{}, ctx.Err()
+		}
+		e.logger.Error("failed to send request", logger.String("error", err.Error()))
+		return ASRResult{}, errors.NewExternalError("asr", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		e.logger.Error("received non-200 response", logger.String("status", fmt.Sprintf("%d", resp.StatusCode)), logger.String("body", string(message)))
+		return ASRResult{}, errors.NewExternalError("asr", fmt.Errorf("status code: %d", resp.StatusCode))
+	}
+
+	var response struct {
+		Text     string  `json:"text"`
+		Duration float64 `json:"duration"`
+		Segments []struct {
+			Start float64 `json:"start"`
+			End   float64 `json:"end"`
+			Text  string  `json:"text"`
+		} `json:"segments"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		e.logger.Error("failed to decode response", logger.String("error", err.Error()))
+		return ASRResult{}, errors.NewExternalError("asr", err)
+	}
+
+	// whisper 不区分说话人
+	result := ASRResult{Text: response.Text, AudioDuration: secondsToDuration(response.Duration)}
+	for _, segment := range response.Segments {
+		text := strings.TrimSpace(segment.Text)
+		if text == "" {
+			continue
+		}
+		result.Segments = append(result.Segments, &transcript.Segment{
+			Index: len(result.Segments),
+			Start: secondsToDuration(segment.Start),
+			End:   secondsToDuration(segment.End),
+			Text:  text,
+		})
+	}
+
+	e.logger.Info("whisper transcription finished", logger.String("file", audio.Path))
+	return result, nil
+}
+
+// newBody 构造 multipart 请求体，边读文件边发送，避免将整个音频读入内存
+func (e *WhisperASREngine) newBody(path string) (io.ReadCloser, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, "", errors.NewInternalError("failed to open audio file", err)
+	}
+
+	reader, pipe := io.Pipe()
+	writer := multipart.NewWriter(pipe)
+	fields := map[string]string{
+		"model":           e.model,
+		"language":        e.language,
+		"response_format": "verbose_json",
+	}
+	go func() {
+		defer file.Close()
+		pipe.CloseWithError(writeWhisperForm(writer, file, filepath.Base(path), fields))
+	}()
+	return reader, writer.FormDataContentType(), nil
+}
+
+func writeWhisperForm(writer *multipart.Writer, file io.Reader, fileName string, fields map[string]string) error {
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := writer.WriteField(name, value); err != nil {
+			return err
+		}
+	}
+	part, err := writer.CreateFormFile("file", fileName)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Millisecond)
+}
@@ -1,13 +1,18 @@
 package external
 
 import (
+	"context"
+	stdErrors "errors"
 	"fmt"
 	asr "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/asr/v20190614"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common"
 	"github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/profile"
 	"iwut-smartclass-backend/internal/domain/errors"
+	"iwut-smartclass-backend/internal/domain/transcript"
 	"iwut-smartclass-backend/internal/infrastructure/logger"
 	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -28,8 +33,11 @@ func NewASRService(secretId, secretKey string, logger logger.Logger) (*ASRServic
 	return &ASRService{client: client, logger: logger}, nil
 }
 
-// Recognize 识别音频
-func (s *ASRService) Recognize(audioFilePath string) (string, error) {
+// ErrASRTaskFailed ASR任务在服务端执行失败，需要重新创建任务
+var ErrASRTaskFailed = stdErrors.New("asr task failed")
+
+// CreateTask 创建识别任务，返回任务ID
+func (s *ASRService) CreateTask(ctx context.Context, audioFilePath string) (uint64, error) {
 	// 配置识别参数
 	request := asr.NewCreateRecTaskRequest()
 	request.EngineModelType = common.StringPtr("16k_zh_dialect")
@@ -43,47 +51,111 @@ func (s *ASRService) Recognize(audioFilePath string) (string, error) {
 
 	s.logger.Info("creating ASR task", logger.String("file", audioFilePath))
 
-	response, err := s.client.CreateRecTask(request)
+	response, err := s.client.CreateRecTaskWithContext(ctx, request)
 	if err != nil {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
 		s.logger.Error("failed to create ASR task", logger.String("error", err.Error()))
-		return "", errors.NewExternalError("asr", err)
+		return 0, errors.NewExternalError("asr", err)
 	}
 
-	taskId := response.Response.Data.TaskId
-	s.logger.Info("ASR task created", logger.String("taskId", fmt.Sprintf("%d", *taskId)))
+	taskId := *response.Response.Data.TaskId
+	s.logger.Info("ASR task created", logger.String("taskId", fmt.Sprintf("%d", taskId)))
+	return taskId, nil
+}
 
-	// 查询识别结果
+// WaitTask 轮询识别任务直到结束或 ctx 取消，返回去除时间戳后的文本与带时间戳的片段
+func (s *ASRService) WaitTask(ctx context.Context, taskId uint64) (ASRResult, error) {
 	for {
 		resultRequest := asr.NewDescribeTaskStatusRequest()
-		resultRequest.TaskId = taskId
+		resultRequest.TaskId = common.Uint64Ptr(taskId)
 
-		resultResponse, err := s.client.DescribeTaskStatus(resultRequest)
+		resultResponse, err := s.client.DescribeTaskStatusWithContext(ctx, resultRequest)
 		if err != nil {
+			if ctx.Err() != nil {
+				return ASRResult{}, ctx.Err()
+			}
 			s.logger.Error("failed to get task status", logger.String("error", err.Error()))
-			return "", errors.NewExternalError("asr", err)
+			return ASRResult{}, errors.NewExternalError("asr", err)
 		}
 
 		if *resultResponse.Response.Data.Status == 2 {
-			s.logger.Info("ASR task finished", logger.String("taskId", fmt.Sprintf("%d", *taskId)))
-			resultText := *resultResponse.Response.Data.Result
-
-			// 使用正则表达式移除时间戳
-			re := regexp.MustCompile(`\[\d{1,3}:\d{1,2}\.\d{3},\d{1,3}:\d{1,2}\.\d{3},\d]\s*`)
-			cleanedText := re.ReplaceAllString(resultText, "")
-
-			return cleanedText, nil
+			s.logger.Info("ASR task finished", logger.String("taskId", fmt.Sprintf("%d", taskId)))
+			result := parseTencentResult(*resultResponse.Response.Data.Result)
+			if resultResponse.Response.Data.AudioDuration != nil {
+				result.AudioDuration = secondsToDuration(*resultResponse.Response.Data.AudioDuration)
+			}
+			return result, nil
 		} else if *resultResponse.Response.Data.Status == 3 {
 			errorMsg := ""
 			if resultResponse.Response.Data.ErrorMsg != nil {
 				errorMsg = *resultResponse.Response.Data.ErrorMsg
 			}
 			s.logger.Error("ASR task failed", logger.String("error", errorMsg))
-			return "", errors.NewExternalError("asr", fmt.Errorf("task failed: %s", errorMsg))
+			return ASRResult{}, errors.NewExternalError("asr", fmt.Errorf("%w: %s", ErrASRTaskFailed, errorMsg))
 		}
 
 		// 20 秒查询一次
-		time.Sleep(20 * time.Second)
+		select {
+		case <-ctx.Done():
+			s.logger.Info("stopped waiting for ASR task", logger.String("taskId", fmt.Sprintf("%d", taskId)))
+			return ASRResult{}, ctx.Err()
+		case <-time.After(20 * time.Second):
+		}
+	}
+}
+
+// 识别结果中每句的时间戳与说话人前缀，如 [0:1.020,0:3.460,0]
+var (
+	asrPrefixPattern = regexp.MustCompile(`\[\d{1,3}:\d{1,2}\.\d{3},\d{1,3}:\d{1,2}\.\d{3},\d]\s*`)
+	asrLinePattern   = regexp.MustCompile(`^\[([\d:.]+),([\d:.]+),(\d+)\]\s*(.*)$`)
+)
+
+// parseTencentResult 解析识别结果，全文去除时间戳前缀，同时保留每句的时间与说话人
+func parseTencentResult(resultText string) ASRResult {
+	result := ASRResult{Text: asrPrefixPattern.ReplaceAllString(resultText, "")}
+	for _, line := range strings.Split(resultText, "\n") {
+		match := asrLinePattern.FindStringSubmatch(strings.TrimSpace(line))
+		if match == nil {
+			continue
+		}
+		start, startErr := parseASRTimestamp(match[1])
+		end, endErr := parseASRTimestamp(match[2])
+		speaker, speakerErr := strconv.Atoi(match[3])
+		text := strings.TrimSpace(match[4])
+		if startErr != nil || endErr != nil || speakerErr != nil || text == "" {
+			continue
+		}
+		result.Segments = append(result.Segments, &transcript.Segment{
+			Index:   len(result.Segments),
+			Start:   start,
+			End:     end,
+			Speaker: speaker,
+			Text:    text,
+		})
+	}
+	return result
+}
+
+// parseASRTimestamp 解析 [时:]分:秒.毫秒 格式的时间戳
+func parseASRTimestamp(value string) (time.Duration, error) {
+	parts := strings.Split(value, ":")
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil {
+		return 0, err
+	}
+	total := time.Duration(seconds * float64(time.Second))
+	unit := time.Minute
+	for i := len(parts) - 2; i >= 0; i-- {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return 0, err
+		}
+		total += time.Duration(n) * unit
+		unit *= 60
 	}
+	return total.Round(time.Millisecond), nil
 }
 
 func int64Ptr(i int64) *int64 {
@@ -0,0 +1,76 @@
+package external
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"iwut-smartclass-backend/internal/domain/user"
+	"iwut-smartclass-backend/internal/infrastructure/cache"
+	"iwut-smartclass-backend/internal/infrastructure/config"
+	"iwut-smartclass-backend/internal/infrastructure/logger"
+)
+
+// CachedUserService 缓存令牌对应的用户信息，避免每次请求和任务都调用用户服务
+type CachedUserService struct {
+	inner  user.ExternalService
+	cache  *cache.LRU[string, user.User]
+	logger logger.Logger
+}
+
+// NewCachedUserService 创建带缓存的用户服务，ttl 为 0 时不缓存
+func NewCachedUserService(inner user.ExternalService, size int, ttl time.Duration, logger logger.Logger) *CachedUserService {
+	return &CachedUserService{
+		inner:  inner,
+		cache:  cache.NewLRU[string, user.User](size, ttl),
+		logger: logger,
+	}
+}
+
+var (
+	cachedUserService     *CachedUserService
+	cachedUserServiceOnce sync.Once
+)
+
+// SharedUserService 返回全局带缓存的用户服务，HTTP 请求与后台任务共用同一份缓存
+func SharedUserService(cfg *config.Config, logger logger.Logger) *CachedUserService {
+	cachedUserServiceOnce.Do(func() {
+		cachedUserService = NewCachedUserService(
+			NewUserService(cfg, logger),
+			cfg.UserCacheSize,
+			time.Duration(cfg.UserCacheTtl)*time.Second,
+			logger,
+		)
+	})
+	return cachedUserService
+}
+
+// GetUserInfo 获取用户信息，命中缓存时不请求用户服务
+func (s *CachedUserService) GetUserInfo(ctx context.Context, token string) (*user.User, error) {
+	key := tokenKey(token)
+	if cached, ok := s.cache.Get(key); ok {
+		return &cached, nil
+	}
+
+	userInfo, err := s.inner.GetUserInfo(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// 缓存不超过令牌的有效期，令牌过期后重新向用户服务校验
+	expiry, _ := user.TokenExpiry(token)
+	if !expiry.IsZero() && !time.Now().Before(expiry) {
+		return userInfo, nil
+	}
+	s.cache.SetUntil(key, *userInfo, expiry)
+	s.logger.Debug("user info cached", logger.String("account", userInfo.Account))
+	return userInfo, nil
+}
+
+// tokenKey 缓存键使用令牌摘要，内存中不保留令牌原文
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/tencentyun/cos-go-sdk-v5"
 	"iwut-smartclass-backend/internal/domain/errors"
+	"iwut-smartclass-backend/internal/infrastructure/config"
 	"iwut-smartclass-backend/internal/infrastructure/logger"
 )
 
@@ -33,13 +34,30 @@ func NewCOSService(secretId, secretKey, bucketURL string, logger logger.Logger)
 	return &COSService{client: client, logger: logger}, nil
 }
 
+// NewCOSServiceFromConfig 使用第一组腾讯云密钥创建COS服务，存储桶属于该密钥的账号，不参与轮换；
+// 未配置密钥时返回 nil
+func NewCOSServiceFromConfig(cfg *config.Config, logger logger.Logger) (*COSService, error) {
+	pool, err := SharedCredentialPool(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	if pool.Len() == 0 {
+		return nil, nil
+	}
+	credential, err := pool.Get(0)
+	if err != nil {
+		return nil, err
+	}
+	return NewCOSService(credential.SecretID, credential.SecretKey, cfg.BucketUrl, logger)
+}
+
 // UploadFile 上传文件
-func (s *COSService) UploadFile(localFilePath, remoteFilePath string) error {
+func (s *COSService) UploadFile(ctx context.Context, localFilePath, remoteFilePath string) error {
 	s.logger.Info("uploading file to COS",
 		logger.String("local", localFilePath),
 		logger.String("remote", remoteFilePath),
 	)
-	_, err := s.client.Object.PutFromFile(context.Background(), remoteFilePath, localFilePath, nil)
+	_, err := s.client.Object.PutFromFile(ctx, remoteFilePath, localFilePath, nil)
 	if err != nil {
 		s.logger.Error("failed to upload file", logger.String("error", err.Error()))
 		return errors.NewExternalError("cos", fmt.Errorf("failed to upload file: %w", err))
@@ -49,12 +67,12 @@ func (s *COSService) UploadFile(localFilePath, remoteFilePath string) error {
 }
 
 // DownloadFile 下载文件
-func (s *COSService) DownloadFile(remoteFilePath, localFilePath string) error {
+func (s *COSService) DownloadFile(ctx context.Context, remoteFilePath, localFilePath string) error {
 	s.logger.Info("downloading file from COS",
 		logger.String("remote", remoteFilePath),
 		logger.String("local", localFilePath),
 	)
-	_, err := s.client.Object.GetToFile(context.Background(), remoteFilePath, localFilePath, nil)
+	_, err := s.client.Object.GetToFile(ctx, remoteFilePath, localFilePath, nil)
 	if err != nil {
 		s.logger.Error("failed to download file", logger.String("error", err.Error()))
 		return errors.NewExternalError("cos", fmt.Errorf("failed to download file: %w", err))
@@ -64,9 +82,9 @@ func (s *COSService) DownloadFile(remoteFilePath, localFilePath string) error {
 }
 
 // DeleteFile 删除文件
-func (s *COSService) DeleteFile(remoteFilePath string) error {
+func (s *COSService) DeleteFile(ctx context.Context, remoteFilePath string) error {
 	s.logger.Info("deleting file from COS", logger.String("remote", remoteFilePath))
-	_, err := s.client.Object.Delete(context.Background(), remoteFilePath)
+	_, err := s.client.Object.Delete(ctx, remoteFilePath)
 	if err != nil {
 		s.logger.Error("failed to delete file", logger.String("error", err.Error()))
 		return errors.NewExternalError("cos", fmt.Errorf("failed to delete file: %w", err))
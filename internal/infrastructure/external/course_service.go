@@ -1,6 +1,7 @@
 package external
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -16,9 +17,9 @@ import (
 
 // CourseExternalService 课程外部服务接口
 type CourseExternalService interface {
-	GetSchedule(token, date, courseName string) (*ScheduleResponse, error)
-	SearchLiveCourse(token string, subID, courseID int) (map[string]interface{}, error)
-	GetVideoAuthKey(token string, courseID, subID int) (string, error)
+	GetSchedule(ctx context.Context, token, date, courseName string) (*ScheduleResponse, error)
+	SearchLiveCourse(ctx context.Context, token string, subID, courseID int) (map[string]interface{}, error)
+	GetVideoAuthKey(ctx context.Context, token string, courseID, subID int) (string, error)
 }
 
 // ScheduleService 课程表服务
@@ -53,11 +54,11 @@ type ScheduleResponse struct {
 }
 
 // GetSchedule 获取课程表
-func (s *ScheduleService) GetSchedule(token, date, courseName string) (*ScheduleResponse, error) {
+func (s *ScheduleService) GetSchedule(ctx context.Context, token, date, courseName string) (*ScheduleResponse, error) {
 	url := fmt.Sprintf("%s?start_at=%s&end_at=%s&token=%s", s.cfg.GetWeekSchedules, date, date, token)
 	s.logger.Debug("sending request to get schedule", logger.String("url", url))
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		s.logger.Error("failed to create request", logger.String("error", err.Error()))
 		return nil, errors.NewExternalError("schedule service", err)
@@ -67,6 +68,9 @@ func (s *ScheduleService) GetSchedule(token, date, courseName string) (*Schedule
 	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		s.logger.Error("failed to send request", logger.String("error", err.Error()))
 		return nil, errors.NewExternalError("schedule service", err)
 	}
@@ -164,11 +168,11 @@ func NewLiveCourseService(cfg *config.Config, logger logger.Logger) *LiveCourseS
 }
 
 // SearchLiveCourse 搜索直播课程
-func (s *LiveCourseService) SearchLiveCourse(token string, subID, courseID int) (map[string]interface{}, error) {
+func (s *LiveCourseService) SearchLiveCourse(ctx context.Context, token string, subID, courseID int) (map[string]interface{}, error) {
 	url := fmt.Sprintf("%s?all=1&course_id=%d&sub_id=%d", s.cfg.SearchLiveCourseList, courseID, subID)
 	s.logger.Debug("sending request to search live course", logger.String("url", url))
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		s.logger.Error("failed to create request", logger.String("error", err.Error()))
 		return nil, errors.NewExternalError("live course service", err)
@@ -179,6 +183,9 @@ func (s *LiveCourseService) SearchLiveCourse(token string, subID, courseID int)
 	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		s.logger.Error("failed to send request", logger.String("error", err.Error()))
 		return nil, errors.NewExternalError("live course service", err)
 	}
@@ -326,10 +333,10 @@ func NewVideoAuthService(cfg *config.Config, logger logger.Logger) *VideoAuthSer
 }
 
 // GetVideoAuthKey 获取视频认证密钥
-func (s *VideoAuthService) GetVideoAuthKey(token string, courseID, subID int) (string, error) {
+func (s *VideoAuthService) GetVideoAuthKey(ctx context.Context, token string, courseID, subID int) (string, error) {
 	url := fmt.Sprintf("%s?all=1&course_id=%d&sub_id=%d&token=%s", s.cfg.SearchLiveCourseList, courseID, subID, token)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		s.logger.Error("failed to create request", logger.String("error", err.Error()))
 		return "", errors.NewExternalError("video auth service", err)
@@ -339,6 +346,9 @@ func (s *VideoAuthService) GetVideoAuthKey(token string, courseID, subID int) (s
 	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
 		s.logger.Error("failed to send request", logger.String("error", err.Error()))
 		return "", errors.NewExternalError("video auth service", err)
 	}
@@ -0,0 +1,292 @@
+package external
+
+import (
+	stdErrors "errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	sdkErrors "github.com/tencentcloud/tencentcloud-sdk-go/tencentcloud/common/errors"
+	"iwut-smartclass-backend/internal/domain/errors"
+	"iwut-smartclass-backend/internal/infrastructure/config"
+	"iwut-smartclass-backend/internal/infrastructure/logger"
+)
+
+// 密钥轮换策略
+const (
+	CredentialStrategyRoundRobin = "round_robin"
+	CredentialStrategyLeastUsed  = "least_used"
+)
+
+// ErrNoCredentialAvailable 所有密钥都在冷却中
+var ErrNoCredentialAvailable = stdErrors.New("no tencent credential available")
+
+// 表示额度耗尽、欠费或鉴权失败的错误码前缀，命中后密钥进入冷却
+var benchErrorCodes = []string{
+	"AuthFailure",
+	"UnauthorizedOperation",
+	"LimitExceeded",
+	"RequestLimitExceeded",
+	"ResourceUnavailable",
+	"FailedOperation.UserHasNoAmount",
+	"FailedOperation.UserHasNoFreeAmount",
+	"FailedOperation.ServiceIsolate",
+	"FailedOperation.UserNotRegistered",
+}
+
+// Credential 一组腾讯云密钥
+type Credential struct {
+	SecretID  string
+	SecretKey string
+}
+
+// CredentialStats 密钥使用情况
+type CredentialStats struct {
+	Index         int
+	SecretID      string // 已脱敏
+	Tasks         int64  // 创建的识别任务数
+	AudioDuration time.Duration
+	Failures      int64
+	LastUsedAt    time.Time
+	LastError     string
+	BenchedUntil  time.Time
+}
+
+type credentialState struct {
+	credential    Credential
+	tasks         int64
+	audioDuration time.Duration
+	failures      int64
+	lastUsedAt    time.Time
+	lastError     string
+	benchedUntil  time.Time
+}
+
+// CredentialPool 腾讯云密钥池，按策略轮换密钥，额度或鉴权出错的密钥冷却一段时间后再使用。
+// 计数只保存在内存中，重启后清零
+type CredentialPool struct {
+	mu       sync.Mutex
+	states   []*credentialState
+	strategy string
+	cooldown time.Duration
+	next     int
+	logger   logger.Logger
+}
+
+// NewCredentialPool 创建密钥池，SecretId 与 SecretKey 必须一一对应
+func NewCredentialPool(secretIds, secretKeys []string, strategy string, cooldown time.Duration, logger logger.Logger) (*CredentialPool, error) {
+	if len(secretIds) != len(secretKeys) {
+		return nil, errors.NewInternalError("invalid tencent credentials", fmt.Errorf("%d secret ids but %d secret keys", len(secretIds), len(secretKeys)))
+	}
+	switch strategy {
+	case "":
+		strategy = CredentialStrategyRoundRobin
+	case CredentialStrategyRoundRobin, CredentialStrategyLeastUsed:
+	default:
+		return nil, errors.NewInternalError("invalid tencent credentials", fmt.Errorf("unknown key strategy %q", strategy))
+	}
+
+	states := make([]*credentialState, 0, len(secretIds))
+	for i := range secretIds {
+		if secretIds[i] == "" || secretKeys[i] == "" {
+			return nil, errors.NewInternalError("invalid tencent credentials", fmt.Errorf("credential %d has an empty secret id or key", i))
+		}
+		states = append(states, &credentialState{
+			credential: Credential{SecretID: secretIds[i], SecretKey: secretKeys[i]},
+		})
+	}
+
+	return &CredentialPool{
+		states:   states,
+		strategy: strategy,
+		cooldown: cooldown,
+		logger:   logger,
+	}, nil
+}
+
+// NewCredentialPoolFromConfig 根据配置创建密钥池
+func NewCredentialPoolFromConfig(cfg *config.Config, logger logger.Logger) (*CredentialPool, error) {
+	return NewCredentialPool(
+		cfg.TencentSecretId,
+		cfg.TencentSecretKey,
+		cfg.TencentKeyStrategy,
+		time.Duration(cfg.TencentKeyCooldown)*time.Second,
+		logger,
+	)
+}
+
+var (
+	credentialPool     *CredentialPool
+	credentialPoolOnce sync.Once
+	credentialPoolErr  error
+)
+
+// SharedCredentialPool 返回全局密钥池，首次调用时根据配置创建，各任务共享同一份使用计数与冷却状态
+func SharedCredentialPool(cfg *config.Config, logger logger.Logger) (*CredentialPool, error) {
+	credentialPoolOnce.Do(func() {
+		credentialPool, credentialPoolErr = NewCredentialPoolFromConfig(cfg, logger)
+	})
+	return credentialPool, credentialPoolErr
+}
+
+// Strategy 返回轮换策略
+func (p *CredentialPool) Strategy() string {
+	return p.strategy
+}
+
+// Len 返回密钥数量
+func (p *CredentialPool) Len() int {
+	return len(p.states)
+}
+
+// Acquire 按策略选取一组未冷却的密钥，并计入一次使用
+func (p *CredentialPool) Acquire() (int, Credential, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(p.states) == 0 {
+		return 0, Credential{}, errors.NewInternalError("no tencent credentials configured", fmt.Errorf("TENCENT_SECRET_ID is empty"))
+	}
+
+	now := time.Now()
+	index := -1
+	switch p.strategy {
+	case CredentialStrategyLeastUsed:
+		for i, state := range p.states {
+			if now.Before(state.benchedUntil) {
+				continue
+			}
+			if index == -1 || state.tasks < p.states[index].tasks {
+				index = i
+			}
+		}
+	default:
+		for offset := 0; offset < len(p.states); offset++ {
+			i := (p.next + offset) % len(p.states)
+			if !now.Before(p.states[i].benchedUntil) {
+				index = i
+				p.next = i + 1
+				break
+			}
+		}
+	}
+
+	if index == -1 {
+		return 0, Credential{}, errors.NewExternalError("asr", fmt.Errorf("%w until %s", ErrNoCredentialAvailable, p.earliestRelease().Format(time.RFC3339)))
+	}
+
+	state := p.states[index]
+	state.tasks++
+	state.lastUsedAt = now
+	return index, state.credential, nil
+}
+
+// Get 返回指定序号的密钥，用于查询该密钥创建的任务，不检查冷却状态
+func (p *CredentialPool) Get(index int) (Credential, error) {
+	if index < 0 || index >= len(p.states) {
+		return Credential{}, errors.NewInternalError("invalid tencent credential index", fmt.Errorf("index %d out of range", index))
+	}
+	return p.states[index].credential, nil
+}
+
+// RecordAudio 记录密钥识别的音频时长
+func (p *CredentialPool) RecordAudio(index int, duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if index >= 0 && index < len(p.states) {
+		p.states[index].audioDuration += duration
+	}
+}
+
+// Report 记录密钥调用失败，额度或鉴权错误使密钥进入冷却
+func (p *CredentialPool) Report(index int, err error) {
+	if err == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if index < 0 || index >= len(p.states) {
+		return
+	}
+	state := p.states[index]
+	state.failures++
+	state.lastError = err.Error()
+
+	if code, ok := IsCredentialError(err); ok && p.cooldown > 0 {
+		state.benchedUntil = time.Now().Add(p.cooldown)
+		p.logger.Warn("tencent credential benched",
+			logger.String("secret_id", maskSecretID(state.credential.SecretID)),
+			logger.String("code", code),
+			logger.String("until", state.benchedUntil.Format(time.RFC3339)),
+		)
+	}
+}
+
+// Release 结束指定密钥的冷却
+func (p *CredentialPool) Release(index int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if index < 0 || index >= len(p.states) {
+		return errors.NewNotFoundError("credential")
+	}
+	p.states[index].benchedUntil = time.Time{}
+	return nil
+}
+
+// Stats 返回各密钥的使用情况
+func (p *CredentialPool) Stats() []CredentialStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]CredentialStats, 0, len(p.states))
+	for i, state := range p.states {
+		stats = append(stats, CredentialStats{
+			Index:         i,
+			SecretID:      maskSecretID(state.credential.SecretID),
+			Tasks:         state.tasks,
+			AudioDuration: state.audioDuration,
+			Failures:      state.failures,
+			LastUsedAt:    state.lastUsedAt,
+			LastError:     state.lastError,
+			BenchedUntil:  state.benchedUntil,
+		})
+	}
+	return stats
+}
+
+func (p *CredentialPool) earliestRelease() time.Time {
+	var earliest time.Time
+	for _, state := range p.states {
+		if earliest.IsZero() || state.benchedUntil.Before(earliest) {
+			earliest = state.benchedUntil
+		}
+	}
+	return earliest
+}
+
+// IsCredentialError 判断错误是否由额度耗尽、欠费或鉴权失败引起，返回腾讯云错误码
+func IsCredentialError(err error) (string, bool) {
+	var sdkErr *sdkErrors.TencentCloudSDKError
+	if !stdErrors.As(err, &sdkErr) {
+		return "", false
+	}
+	code := sdkErr.GetCode()
+	for _, prefix := range benchErrorCodes {
+		if code == prefix || strings.HasPrefix(code, prefix+".") {
+			return code, true
+		}
+	}
+	return code, false
+}
+
+func maskSecretID(secretID string) string {
+	if len(secretID) <= 8 {
+		return "****"
+	}
+	return secretID[:4] + "****" + secretID[len(secretID)-4:]
+}
@@ -3,6 +3,9 @@ package external
 import (
 	"context"
 	"os/exec"
+	"strconv"
+	"strings"
+	"time"
 
 	"iwut-smartclass-backend/internal/domain/errors"
 	"iwut-smartclass-backend/internal/infrastructure/logger"
@@ -43,3 +46,19 @@ func (s *FFmpegService) ConvertVideoToAudio(ctx context.Context, inputFile, outp
 	)
 	return nil
 }
+
+// ProbeDuration 使用 ffprobe 读取音视频文件的时长
+func (s *FFmpegService) ProbeDuration(ctx context.Context, file string) (time.Duration, error) {
+	cmd := exec.CommandContext(ctx, "ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", file)
+	output, err := cmd.Output()
+	if err != nil {
+		s.logger.Error("failed to probe duration", logger.String("file", file), logger.String("error", err.Error()))
+		return 0, errors.NewInternalError("failed to probe duration", err)
+	}
+
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	if err != nil {
+		return 0, errors.NewInternalError("failed to parse duration", err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,131 @@
+package external
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"iwut-smartclass-backend/internal/infrastructure/config"
+	"iwut-smartclass-backend/internal/infrastructure/logger"
+)
+
+// LLMProvider 大模型服务
+type LLMProvider interface {
+	// Name 返回服务名称
+	Name() string
+	// Complete 生成完整文本
+	Complete(ctx context.Context, prompt, userInput string) (LLMResult, error)
+	// Stream 以流式方式生成文本，每收到一段增量文本调用 onDelta，出错时结果中保留已收到的文本
+	Stream(ctx context.Context, prompt, userInput string, onDelta func(delta string)) (LLMResult, error)
+}
+
+// LLMResult 大模型调用结果
+type LLMResult struct {
+	Content          string
+	Token            uint32
+	PromptTokens     uint32
+	CompletionTokens uint32
+	Provider         string
+	Model            string
+}
+
+// Source 返回实际生成文本的服务与模型，如 deepseek/deepseek-chat
+func (r LLMResult) Source() string {
+	if r.Provider == "" {
+		return r.Model
+	}
+	return fmt.Sprintf("%s/%s", r.Provider, r.Model)
+}
+
+// LLMStatusError 大模型服务返回的非 200 状态码
+type LLMStatusError struct {
+	StatusCode int
+}
+
+func (e *LLMStatusError) Error() string {
+	return fmt.Sprintf("status code: %d", e.StatusCode)
+}
+
+// FallbackLLM 按顺序调用多个大模型服务，遇到 5xx、限流或超时时切换到下一个
+type FallbackLLM struct {
+	providers []LLMProvider
+	logger    logger.Logger
+}
+
+// NewFallbackLLM 创建带回退的大模型服务
+func NewFallbackLLM(providers []LLMProvider, logger logger.Logger) *FallbackLLM {
+	return &FallbackLLM{
+		providers: providers,
+		logger:    logger,
+	}
+}
+
+// NewLLMProvider 根据配置创建大模型服务
+func NewLLMProvider(cfg *config.Config, logger logger.Logger) *FallbackLLM {
+	var providers []LLMProvider
+	for _, provider := range cfg.ResolveLLMProviders() {
+		providers = append(providers, NewOpenAIService(provider, logger))
+	}
+	return NewFallbackLLM(providers, logger)
+}
+
+// Name 返回服务名称
+func (f *FallbackLLM) Name() string {
+	return "fallback"
+}
+
+// Complete 依次尝试各个服务生成完整文本
+func (f *FallbackLLM) Complete(ctx context.Context, prompt, userInput string) (LLMResult, error) {
+	var result LLMResult
+	var err error
+	for i, provider := range f.providers {
+		result, err = provider.Complete(ctx, prompt, userInput)
+		if err == nil || !f.shouldFallback(ctx, i, provider, err) {
+			return result, err
+		}
+	}
+	return result, err
+}
+
+// Stream 依次尝试各个服务流式生成文本，已输出部分文本后不再切换，避免重复内容
+func (f *FallbackLLM) Stream(ctx context.Context, prompt, userInput string, onDelta func(delta string)) (LLMResult, error) {
+	var result LLMResult
+	var err error
+	for i, provider := range f.providers {
+		result, err = provider.Stream(ctx, prompt, userInput, onDelta)
+		if err == nil || result.Content != "" || !f.shouldFallback(ctx, i, provider, err) {
+			return result, err
+		}
+	}
+	return result, err
+}
+
+// shouldFallback 判断失败后是否切换到下一个服务
+func (f *FallbackLLM) shouldFallback(ctx context.Context, index int, provider LLMProvider, err error) bool {
+	if index == len(f.providers)-1 || ctx.Err() != nil || !isRetryableLLMError(err) {
+		return false
+	}
+	f.logger.Warn("LLM provider failed, falling back",
+		logger.String("provider", provider.Name()),
+		logger.String("next", f.providers[index+1].Name()),
+		logger.String("error", err.Error()),
+	)
+	return true
+}
+
+// isRetryableLLMError 5xx、429 以及超时、连接失败等网络错误可以换用其他服务
+func isRetryableLLMError(err error) bool {
+	var statusErr *LLMStatusError
+	if stdErrors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	var urlErr *url.Error
+	if stdErrors.As(err, &urlErr) {
+		return true
+	}
+	var netErr net.Error
+	return (stdErrors.As(err, &netErr) && netErr.Timeout()) || stdErrors.Is(err, context.DeadlineExceeded)
+}
@@ -1,10 +1,13 @@
 package external
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"iwut-smartclass-backend/internal/domain/errors"
@@ -19,8 +22,22 @@ type OpenAIRequest struct {
 		Role    string `json:"role"`
 		Content string `json:"content"`
 	} `json:"messages"`
-	Stream      bool    `json:"stream"`
-	Temperature float32 `json:"temperature"`
+	Stream        bool                 `json:"stream"`
+	StreamOptions *OpenAIStreamOptions `json:"stream_options,omitempty"`
+	Temperature   float32              `json:"temperature"`
+	MaxTokens     int                  `json:"max_tokens,omitempty"`
+}
+
+// OpenAIStreamOptions 流式请求选项
+type OpenAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// OpenAIUsage Token 用量
+type OpenAIUsage struct {
+	PromptTokens     uint32 `json:"prompt_tokens"`
+	CompletionTokens uint32 `json:"completion_tokens"`
+	TotalTokens      uint32 `json:"total_tokens"`
 }
 
 // OpenAIResponse 通用 OpenAI 响应结构
@@ -30,79 +47,92 @@ type OpenAIResponse struct {
 			Content string `json:"content"`
 		} `json:"message"`
 	} `json:"choices"`
-	Usage struct {
-		PromptTokens     uint32 `json:"prompt_tokens"`
-		CompletionTokens uint32 `json:"completion_tokens"`
-		TotalTokens      uint32 `json:"total_tokens"`
-	} `json:"usage"`
+	Usage OpenAIUsage `json:"usage"`
+}
+
+// OpenAIStreamChunk 流式响应的增量数据块，最后一个数据块携带用量
+type OpenAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *OpenAIUsage `json:"usage"`
 }
 
-// OpenAIService OpenAI服务
+// OpenAIService 兼容 OpenAI 接口的大模型服务
 type OpenAIService struct {
-	cfg    *config.Config
-	logger logger.Logger
+	provider config.LLMProvider
+	logger   logger.Logger
 }
 
 // NewOpenAIService 创建OpenAI服务
-func NewOpenAIService(cfg *config.Config, logger logger.Logger) *OpenAIService {
+func NewOpenAIService(provider config.LLMProvider, logger logger.Logger) *OpenAIService {
 	return &OpenAIService{
-		cfg:    cfg,
-		logger: logger,
+		provider: provider,
+		logger:   logger,
 	}
 }
 
-// CallOpenAI 调用 OpenAI API
-func (s *OpenAIService) CallOpenAI(prompt, userInput string) (string, uint32, error) {
-	s.logger.Info("creating OpenAI request")
+// Name 返回服务名称
+func (s *OpenAIService) Name() string {
+	return s.provider.Name
+}
 
-	requestBody, err := json.Marshal(OpenAIRequest{
-		Model: s.cfg.OpenaiModel,
-		Messages: []struct {
-			Role    string `json:"role"`
-			Content string `json:"content"`
-		}{
-			{Role: "system", Content: prompt},
-			{Role: "user", Content: userInput},
-		},
-		Stream:      false,
-		Temperature: s.cfg.Temperature,
-	})
-	if err != nil {
-		s.logger.Error("failed to marshal request body", logger.String("error", err.Error()))
-		return "", 0, errors.NewInternalError("failed to marshal request", err)
+// Complete 调用大模型生成文本
+func (s *OpenAIService) Complete(ctx context.Context, prompt, userInput string) (LLMResult, error) {
+	content, usage, err := s.CallOpenAI(ctx, prompt, userInput)
+	return s.result(content, usage), err
+}
+
+// Stream 以流式方式调用大模型
+func (s *OpenAIService) Stream(ctx context.Context, prompt, userInput string, onDelta func(delta string)) (LLMResult, error) {
+	content, usage, err := s.StreamOpenAI(ctx, prompt, userInput, onDelta)
+	return s.result(content, usage), err
+}
+
+func (s *OpenAIService) result(content string, usage OpenAIUsage) LLMResult {
+	return LLMResult{
+		Content:          content,
+		Token:            usage.TotalTokens,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		Provider:         s.provider.Name,
+		Model:            s.provider.Model,
 	}
+}
 
-	req, err := http.NewRequest("POST", s.cfg.OpenaiEndpoint, bytes.NewBuffer(requestBody))
+// CallOpenAI 调用 OpenAI API，返回生成的文本与 Token 用量
+func (s *OpenAIService) CallOpenAI(ctx context.Context, prompt, userInput string) (string, OpenAIUsage, error) {
+	s.logger.Info("creating OpenAI request", logger.String("provider", s.provider.Name))
+
+	req, err := s.newRequest(ctx, prompt, userInput, false)
 	if err != nil {
-		s.logger.Error("failed to create request", logger.String("error", err.Error()))
-		return "", 0, errors.NewInternalError("failed to create request", err)
+		return "", OpenAIUsage{}, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.cfg.OpenaiKey))
-
 	client := &http.Client{Timeout: 10 * time.Minute}
 	resp, err := client.Do(req)
 	if err != nil {
 		s.logger.Error("failed to send request", logger.String("error", err.Error()))
-		return "", 0, errors.NewExternalError("openai", err)
+		return "", OpenAIUsage{}, errors.NewExternalError("openai", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		s.logger.Error("received non-200 response", logger.String("status", fmt.Sprintf("%d", resp.StatusCode)))
-		return "", 0, errors.NewExternalError("openai", fmt.Errorf("status code: %d", resp.StatusCode))
+		s.logger.Error("received non-200 response", logger.String("provider", s.provider.Name), logger.String("status", fmt.Sprintf("%d", resp.StatusCode)))
+		return "", OpenAIUsage{}, errors.NewExternalError("openai", &LLMStatusError{StatusCode: resp.StatusCode})
 	}
 
 	var openAIResponse OpenAIResponse
 	if err := json.NewDecoder(resp.Body).Decode(&openAIResponse); err != nil {
 		s.logger.Error("failed to decode response", logger.String("error", err.Error()))
-		return "", 0, errors.NewExternalError("openai", err)
+		return "", OpenAIUsage{}, errors.NewExternalError("openai", err)
 	}
 
 	if len(openAIResponse.Choices) == 0 {
 		s.logger.Error("no choices in response")
-		return "", 0, errors.NewExternalError("openai", fmt.Errorf("no choices in response"))
+		return "", OpenAIUsage{}, errors.NewExternalError("openai", fmt.Errorf("no choices in response"))
 	}
 
 	content := openAIResponse.Choices[0].Message.Content
@@ -112,5 +142,120 @@ func (s *OpenAIService) CallOpenAI(prompt, userInput string) (string, uint32, er
 		logger.String("completion_tokens", fmt.Sprintf("%d", usage.CompletionTokens)),
 		logger.String("total_tokens", fmt.Sprintf("%d", usage.TotalTokens)),
 	)
-	return content, usage.TotalTokens, nil
+	return content, usage, nil
+}
+
+// StreamOpenAI 以流式方式调用 OpenAI API，每收到一段增量文本调用 onDelta。
+// 中途出错时同时返回已收到的文本
+func (s *OpenAIService) StreamOpenAI(ctx context.Context, prompt, userInput string, onDelta func(delta string)) (string, OpenAIUsage, error) {
+	s.logger.Info("creating OpenAI stream request", logger.String("provider", s.provider.Name))
+
+	req, err := s.newRequest(ctx, prompt, userInput, true)
+	if err != nil {
+		return "", OpenAIUsage{}, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	// 流式响应的总时长由 ctx 控制
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		s.logger.Error("failed to send request", logger.String("error", err.Error()))
+		return "", OpenAIUsage{}, errors.NewExternalError("openai", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		s.logger.Error("received non-200 response", logger.String("provider", s.provider.Name), logger.String("status", fmt.Sprintf("%d", resp.StatusCode)))
+		return "", OpenAIUsage{}, errors.NewExternalError("openai", &LLMStatusError{StatusCode: resp.StatusCode})
+	}
+
+	var content strings.Builder
+	var usage OpenAIUsage
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if payload == "[DONE]" {
+			break
+		}
+
+		var chunk OpenAIStreamChunk
+		if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+			s.logger.Error("failed to decode stream chunk", logger.String("error", err.Error()))
+			return content.String(), usage, errors.NewExternalError("openai", err)
+		}
+		if chunk.Usage != nil {
+			usage = *chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content == "" {
+				continue
+			}
+			content.WriteString(choice.Delta.Content)
+			if onDelta != nil {
+				onDelta(choice.Delta.Content)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		s.logger.Error("failed to read stream", logger.String("error", err.Error()))
+		return content.String(), usage, errors.NewExternalError("openai", err)
+	}
+
+	if content.Len() == 0 {
+		s.logger.Error("no content in stream")
+		return "", OpenAIUsage{}, errors.NewExternalError("openai", fmt.Errorf("no content in stream"))
+	}
+
+	s.logger.Info("OpenAI stream finished",
+		logger.String("prompt_tokens", fmt.Sprintf("%d", usage.PromptTokens)),
+		logger.String("completion_tokens", fmt.Sprintf("%d", usage.CompletionTokens)),
+		logger.String("total_tokens", fmt.Sprintf("%d", usage.TotalTokens)),
+	)
+	return content.String(), usage, nil
+}
+
+// newRequest 创建 Chat Completions 请求
+func (s *OpenAIService) newRequest(ctx context.Context, prompt, userInput string, stream bool) (*http.Request, error) {
+	var temperature float32
+	if s.provider.Temperature != nil {
+		temperature = *s.provider.Temperature
+	}
+	body := OpenAIRequest{
+		Model: s.provider.Model,
+		Messages: []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		}{
+			{Role: "system", Content: prompt},
+			{Role: "user", Content: userInput},
+		},
+		Stream:      stream,
+		Temperature: temperature,
+		MaxTokens:   s.provider.MaxTokens,
+	}
+	if stream {
+		body.StreamOptions = &OpenAIStreamOptions{IncludeUsage: true}
+	}
+
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		s.logger.Error("failed to marshal request body", logger.String("error", err.Error()))
+		return nil, errors.NewInternalError("failed to marshal request", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.provider.Endpoint, bytes.NewBuffer(requestBody))
+	if err != nil {
+		s.logger.Error("failed to create request", logger.String("error", err.Error()))
+		return nil, errors.NewInternalError("failed to create request", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", s.provider.Key))
+	return req, nil
 }
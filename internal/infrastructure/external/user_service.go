@@ -1,6 +1,7 @@
 package external
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -28,11 +29,11 @@ func NewUserService(cfg *config.Config, logger logger.Logger) *UserService {
 }
 
 // GetUserInfo 获取用户信息
-func (s *UserService) GetUserInfo(token string) (*user.User, error) {
+func (s *UserService) GetUserInfo(ctx context.Context, token string) (*user.User, error) {
 	url := s.cfg.InfoSimple
 	s.logger.Debug("sending request to get user info", logger.String("url", url))
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		s.logger.Error("failed to create request", logger.String("error", err.Error()))
 		return nil, errors.NewExternalError("user service", err)
@@ -44,6 +45,9 @@ func (s *UserService) GetUserInfo(token string) (*user.User, error) {
 	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		s.logger.Error("failed to send request", logger.String("error", err.Error()))
 		return nil, errors.NewExternalError("user service", err)
 	}
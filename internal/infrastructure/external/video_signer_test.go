@@ -0,0 +1,111 @@
+package external
+
+import (
+	stdErrors "errors"
+	"testing"
+	"time"
+
+	"iwut-smartclass-backend/internal/domain/user"
+)
+
+func TestVideoURLSignerSignAt(t *testing.T) {
+	signer := NewVideoURLSigner(30 * time.Minute)
+
+	tests := []struct {
+		name     string
+		videoURL string
+		user     *user.User
+		authKey  string
+		at       int64
+		want     string
+	}{
+		{
+			name:     "plain url",
+			videoURL: "https://video.example.com/live/abc/index.m3u8",
+			user:     &user.User{ID: 12345, TenantID: 2, Phone: "13812345678"},
+			authKey:  "1a2b3c4d-0000-1111-2222-333344445555",
+			at:       1711420800,
+			want:     "https://video.example.com/live/abc/index.m3u8?auth_key=1a2b3c4d-0000-1111-2222-333344445555&t=12345-1711420800-31beb0ef560e3cb3fe4b448cc6cbcf38",
+		},
+		{
+			name:     "url with query",
+			videoURL: "https://video.example.com/vod/2025/03/26/1111111.mp4?quality=hd",
+			user:     &user.User{ID: 42, TenantID: 7, Phone: "13811110000"},
+			authKey:  "deadbeef",
+			at:       1742954400,
+			want:     "https://video.example.com/vod/2025/03/26/1111111.mp4?quality=hd&auth_key=deadbeef&t=42-1742954400-877c737d9303cb4713e7e9f3f7b56028",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed, err := signer.SignAt(tt.videoURL, tt.user, tt.authKey, time.Unix(tt.at, 0))
+			if err != nil {
+				t.Fatalf("SignAt() error = %v", err)
+			}
+			if signed.URL != tt.want {
+				t.Errorf("SignAt() URL = %q, want %q", signed.URL, tt.want)
+			}
+			if wantExpiry := time.Unix(tt.at, 0).Add(30 * time.Minute); !signed.ExpiresAt.Equal(wantExpiry) {
+				t.Errorf("SignAt() ExpiresAt = %v, want %v", signed.ExpiresAt, wantExpiry)
+			}
+		})
+	}
+}
+
+func TestVideoURLSignerSignAtInvalidURL(t *testing.T) {
+	signer := NewVideoURLSigner(time.Minute)
+	if _, err := signer.SignAt("://bad", &user.User{ID: 1}, "key", time.Now()); err == nil {
+		t.Fatal("SignAt() error = nil, want error")
+	}
+}
+
+func TestVideoURLSignerVerifyAt(t *testing.T) {
+	signer := NewVideoURLSigner(30 * time.Minute)
+	owner := &user.User{ID: 12345, TenantID: 2, Phone: "13812345678"}
+	signedAt := time.Unix(1711420800, 0)
+	signed, err := signer.SignAt("https://video.example.com/live/abc/index.m3u8", owner, "key", signedAt)
+	if err != nil {
+		t.Fatalf("SignAt() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		url  string
+		user *user.User
+		at   time.Time
+		want error
+	}{
+		{"valid", signed.URL, owner, signedAt.Add(time.Minute), nil},
+		{"expired", signed.URL, owner, signed.ExpiresAt, ErrVideoURLExpired},
+		{"other user", signed.URL, &user.User{ID: 54321, TenantID: 2, Phone: "13812345678"}, signedAt, ErrVideoURLInvalid},
+		{"other phone", signed.URL, &user.User{ID: 12345, TenantID: 2, Phone: "13800000000"}, signedAt, ErrVideoURLInvalid},
+		{"other tenant", signed.URL, &user.User{ID: 12345, TenantID: 3, Phone: "13812345678"}, signedAt, ErrVideoURLInvalid},
+		{
+			name: "tampered path",
+			url:  "https://video.example.com/live/xyz/index.m3u8?auth_key=key&t=12345-1711420800-31beb0ef560e3cb3fe4b448cc6cbcf38",
+			user: owner,
+			at:   signedAt,
+			want: ErrVideoURLInvalid,
+		},
+		{
+			name: "known vector",
+			url:  "https://video.example.com/live/abc/index.m3u8?auth_key=key&t=12345-1711420800-31beb0ef560e3cb3fe4b448cc6cbcf38",
+			user: owner,
+			at:   signedAt,
+			want: nil,
+		},
+		{"missing auth key", "https://video.example.com/live/abc/index.m3u8?t=12345-1711420800-31beb0ef560e3cb3fe4b448cc6cbcf38", owner, signedAt, ErrVideoURLInvalid},
+		{"malformed t", "https://video.example.com/live/abc/index.m3u8?auth_key=key&t=12345-abc", owner, signedAt, ErrVideoURLInvalid},
+		{"unsigned", "https://video.example.com/live/abc/index.m3u8", owner, signedAt, ErrVideoURLInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := signer.VerifyAt(tt.url, tt.user, tt.at)
+			if !stdErrors.Is(err, tt.want) {
+				t.Errorf("VerifyAt() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -31,20 +31,23 @@ func (r *CourseRepository) FindBySubID(ctx context.Context, subID int) (*course.
 	defer cancel()
 
 	var result struct {
-		SubID         int
-		CourseID      int
-		Name          string
-		Teacher       string
-		Location      string
-		Date          string
-		Time          string
-		Video         *string
-		Asr           *string
-		SummaryStatus *string
-		SummaryData   *string
-		Model         *string
-		Token         *uint32
-		SummaryUser   *string
+		SubID           int
+		CourseID        int
+		Name            string
+		Teacher         string
+		Location        string
+		Date            string
+		Time            string
+		Video           *string
+		Asr             *string
+		SummaryStatus   *string
+		SummaryStatusAt *int64
+		SummaryData     *string
+		SummaryPartial  *string
+		Model           *string
+		PromptVersion   *string
+		Token           *uint32
+		SummaryUser     *string
 	}
 
 	err := r.db.WithContext(ctx).Table("course").
@@ -79,12 +82,21 @@ func (r *CourseRepository) FindBySubID(ctx context.Context, subID int) (*course.
 	if result.SummaryStatus != nil {
 		c.SummaryStatus = *result.SummaryStatus
 	}
+	if result.SummaryStatusAt != nil && *result.SummaryStatusAt > 0 {
+		c.SummaryStatusAt = time.Unix(*result.SummaryStatusAt, 0)
+	}
 	if result.SummaryData != nil {
 		c.SummaryData = *result.SummaryData
 	}
+	if result.SummaryPartial != nil {
+		c.SummaryPartial = *result.SummaryPartial
+	}
 	if result.Model != nil {
 		c.Model = *result.Model
 	}
+	if result.PromptVersion != nil {
+		c.PromptVersion = *result.PromptVersion
+	}
 	if result.Token != nil {
 		c.Token = *result.Token
 	}
@@ -165,7 +177,10 @@ func (r *CourseRepository) UpdateSummaryStatus(ctx context.Context, subID int, s
 
 	err := r.db.WithContext(ctx).Table("course").
 		Where("sub_id = ?", subID).
-		Update("summary_status", status).Error
+		Updates(map[string]interface{}{
+			"summary_status":    status,
+			"summary_status_at": time.Now().Unix(),
+		}).Error
 
 	if err != nil {
 		r.logger.Error("failed to update summary status", logger.String("error", err.Error()))
@@ -175,19 +190,79 @@ func (r *CourseRepository) UpdateSummaryStatus(ctx context.Context, subID int, s
 	return nil
 }
 
-// UpdateSummary 更新摘要数据
-func (r *CourseRepository) UpdateSummary(ctx context.Context, subID int, summary, model string, token uint32, user string) error {
+// FindStaleGenerating 查找在 before 之前进入生成中状态的课程，没有状态时间的旧数据视为过期
+func (r *CourseRepository) FindStaleGenerating(ctx context.Context, before time.Time) ([]*course.Course, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var results []struct {
+		SubID           int
+		SummaryStatusAt *int64
+		SummaryData     *string
+	}
+
+	err := r.db.WithContext(ctx).Table("course").
+		Select("sub_id", "summary_status_at", "summary_data").
+		Where("summary_status = ?", "generating").
+		Where("(summary_status_at IS NULL OR summary_status_at < ?)", before.Unix()).
+		Find(&results).Error
+
+	if err != nil {
+		r.logger.Error("failed to find stale courses", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	courses := make([]*course.Course, 0, len(results))
+	for _, result := range results {
+		c := &course.Course{
+			SubID:         result.SubID,
+			SummaryStatus: "generating",
+		}
+		if result.SummaryStatusAt != nil && *result.SummaryStatusAt > 0 {
+			c.SummaryStatusAt = time.Unix(*result.SummaryStatusAt, 0)
+		}
+		if result.SummaryData != nil {
+			c.SummaryData = *result.SummaryData
+		}
+		courses = append(courses, c)
+	}
+
+	return courses, nil
+}
+
+// UpdateSummaryPartial 只更新部分摘要
+func (r *CourseRepository) UpdateSummaryPartial(ctx context.Context, subID int, partial string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Table("course").
+		Where("sub_id = ?", subID).
+		Update("summary_partial", partial).Error
+
+	if err != nil {
+		r.logger.Error("failed to update partial summary", logger.String("error", err.Error()))
+		return err
+	}
+
+	return nil
+}
+
+// UpdateSummary 更新摘要数据并清空部分摘要
+func (r *CourseRepository) UpdateSummary(ctx context.Context, subID int, summary, model, promptVersion string, token uint32, user string) error {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	err := r.db.WithContext(ctx).Table("course").
 		Where("sub_id = ?", subID).
 		Updates(map[string]interface{}{
-			"summary_data":   summary,
-			"model":          model,
-			"token":          token,
-			"summary_status": "finished",
-			"summary_user":   user,
+			"summary_data":      summary,
+			"summary_partial":   "",
+			"model":             model,
+			"prompt_version":    promptVersion,
+			"token":             token,
+			"summary_status":    "finished",
+			"summary_status_at": time.Now().Unix(),
+			"summary_user":      user,
 		}).Error
 
 	if err != nil {
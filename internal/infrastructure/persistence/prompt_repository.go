@@ -0,0 +1,161 @@
+package persistence
+
+import (
+	"context"
+	stdErrors "errors"
+	"time"
+
+	_struct "iwut-smartclass-backend/internal/database/struct"
+	"iwut-smartclass-backend/internal/domain/errors"
+	"iwut-smartclass-backend/internal/domain/prompt"
+	"iwut-smartclass-backend/internal/infrastructure/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PromptRepository 提示词模板仓储实现
+type PromptRepository struct {
+	db     *gorm.DB
+	logger logger.Logger
+}
+
+// NewPromptRepository 创建提示词模板仓储
+func NewPromptRepository(db *gorm.DB, logger logger.Logger) *PromptRepository {
+	return &PromptRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// FindLatest 查找作用域下启用的最新版本模板，不存在时返回 nil
+func (r *PromptRepository) FindLatest(ctx context.Context, kind, scope, scopeValue string) (*prompt.Template, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var row _struct.PromptTemplate
+	err := r.db.WithContext(ctx).
+		Where("kind = ? AND scope = ? AND scope_value = ? AND enabled = ?", kind, scope, scopeValue, true).
+		Order("version DESC").
+		First(&row).Error
+	if err != nil {
+		if stdErrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		r.logger.Error("failed to find prompt template", logger.String("error", err.Error()))
+		return nil, err
+	}
+	return toPromptTemplate(&row), nil
+}
+
+// FindByID 根据ID查找模板
+func (r *PromptRepository) FindByID(ctx context.Context, id int) (*prompt.Template, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var row _struct.PromptTemplate
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&row).Error
+	if err != nil {
+		if stdErrors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.NewNotFoundError("prompt template")
+		}
+		r.logger.Error("failed to find prompt template", logger.String("error", err.Error()))
+		return nil, err
+	}
+	return toPromptTemplate(&row), nil
+}
+
+// List 列出模板，kind 为空时列出全部
+func (r *PromptRepository) List(ctx context.Context, kind string) ([]*prompt.Template, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	query := r.db.WithContext(ctx).Order("kind, scope, scope_value, version DESC")
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	var rows []_struct.PromptTemplate
+	if err := query.Find(&rows).Error; err != nil {
+		r.logger.Error("failed to list prompt templates", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	templates := make([]*prompt.Template, 0, len(rows))
+	for i := range rows {
+		templates = append(templates, toPromptTemplate(&rows[i]))
+	}
+	return templates, nil
+}
+
+// Create 保存新版本模板，版本号在同一作用域内递增
+func (r *PromptRepository) Create(ctx context.Context, t *prompt.Template) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var latest _struct.PromptTemplate
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("kind = ? AND scope = ? AND scope_value = ?", t.Kind, t.Scope, t.ScopeValue).
+			Order("version DESC").
+			First(&latest).Error
+		if err != nil && !stdErrors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if t.CreatedAt.IsZero() {
+			t.CreatedAt = time.Now()
+		}
+		row := &_struct.PromptTemplate{
+			Kind:       t.Kind,
+			Scope:      t.Scope,
+			ScopeValue: t.ScopeValue,
+			Version:    latest.Version + 1,
+			Content:    t.Content,
+			Enabled:    t.Enabled,
+			CreatedAt:  t.CreatedAt.Unix(),
+		}
+		if err := tx.Create(row).Error; err != nil {
+			return err
+		}
+		t.ID = row.ID
+		t.Version = row.Version
+		return nil
+	})
+	if err != nil {
+		r.logger.Error("failed to create prompt template", logger.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// SetEnabled 启用或停用模板
+func (r *PromptRepository) SetEnabled(ctx context.Context, id int, enabled bool) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Model(&_struct.PromptTemplate{}).
+		Where("id = ?", id).
+		Update("enabled", enabled)
+	if result.Error != nil {
+		r.logger.Error("failed to update prompt template", logger.String("error", result.Error.Error()))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.NewNotFoundError("prompt template")
+	}
+	return nil
+}
+
+func toPromptTemplate(row *_struct.PromptTemplate) *prompt.Template {
+	return &prompt.Template{
+		ID:         row.ID,
+		Kind:       row.Kind,
+		Scope:      row.Scope,
+		ScopeValue: row.ScopeValue,
+		Version:    row.Version,
+		Content:    row.Content,
+		Enabled:    row.Enabled,
+		CreatedAt:  time.Unix(row.CreatedAt, 0),
+	}
+}
@@ -34,10 +34,12 @@ func (r *SummaryRepository) FindBySubIDAndUser(ctx context.Context, subID int, u
 	defer cancel()
 
 	var results []struct {
-		Summary     string
-		Model       string
-		Token       uint32
-		CreateAtRaw string `gorm:"column:create_at"`
+		Summary       string
+		Partial       *string
+		Model         string
+		PromptVersion string
+		Token         uint32
+		CreateAtRaw   string `gorm:"column:create_at"`
 	}
 
 	err := r.db.WithContext(ctx).Table("summary").
@@ -56,14 +58,19 @@ func (r *SummaryRepository) FindBySubIDAndUser(ctx context.Context, subID int, u
 		if parseErr != nil {
 			r.logger.Warn("failed to parse create_at, using zero time", logger.String("error", parseErr.Error()), logger.String("value", result.CreateAtRaw))
 		}
-		summaries = append(summaries, &summary.Summary{
-			User:     user,
-			SubID:    subID,
-			CreateAt: createAt,
-			Summary:  result.Summary,
-			Model:    result.Model,
-			Token:    result.Token,
-		})
+		s := &summary.Summary{
+			User:          user,
+			SubID:         subID,
+			CreateAt:      createAt,
+			Summary:       result.Summary,
+			Model:         result.Model,
+			PromptVersion: result.PromptVersion,
+			Token:         result.Token,
+		}
+		if result.Partial != nil {
+			s.Partial = *result.Partial
+		}
+		summaries = append(summaries, s)
 	}
 
 	return summaries, nil
@@ -81,12 +88,14 @@ func (r *SummaryRepository) Save(ctx context.Context, s *summary.Summary) error
 	createAtStr := createAt.Format(summaryTimeLayout)
 
 	err := r.db.WithContext(ctx).Table("summary").Create(map[string]interface{}{
-		"user":      s.User,
-		"sub_id":    s.SubID,
-		"create_at": createAtStr,
-		"summary":   s.Summary,
-		"model":     s.Model,
-		"token":     s.Token,
+		"user":           s.User,
+		"sub_id":         s.SubID,
+		"create_at":      createAtStr,
+		"summary":        s.Summary,
+		"partial":        s.Partial,
+		"model":          s.Model,
+		"prompt_version": s.PromptVersion,
+		"token":          s.Token,
 	}).Error
 
 	if err != nil {
@@ -111,9 +120,11 @@ func (r *SummaryRepository) Update(ctx context.Context, s *summary.Summary) erro
 	err := r.db.WithContext(ctx).Table("summary").
 		Where("sub_id = ? AND user = ? AND create_at = ?", s.SubID, s.User, createAtStr).
 		Updates(map[string]interface{}{
-			"summary": s.Summary,
-			"model":   s.Model,
-			"token":   s.Token,
+			"summary":        s.Summary,
+			"partial":        s.Partial,
+			"model":          s.Model,
+			"prompt_version": s.PromptVersion,
+			"token":          s.Token,
 		}).Error
 
 	if err != nil {
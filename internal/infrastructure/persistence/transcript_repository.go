@@ -0,0 +1,91 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	_struct "iwut-smartclass-backend/internal/database/struct"
+	"iwut-smartclass-backend/internal/domain/transcript"
+	"iwut-smartclass-backend/internal/infrastructure/logger"
+
+	"gorm.io/gorm"
+)
+
+// 每批写入的片段数量
+const transcriptBatchSize = 200
+
+// TranscriptRepository 转写片段仓储实现
+type TranscriptRepository struct {
+	db     *gorm.DB
+	logger logger.Logger
+}
+
+// NewTranscriptRepository 创建转写片段仓储
+func NewTranscriptRepository(db *gorm.DB, logger logger.Logger) *TranscriptRepository {
+	return &TranscriptRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// FindBySubID 按时间顺序查找课程的转写片段
+func (r *TranscriptRepository) FindBySubID(ctx context.Context, subID int) ([]*transcript.Segment, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var rows []_struct.TranscriptSegment
+	err := r.db.WithContext(ctx).
+		Where("sub_id = ?", subID).
+		Order("seq ASC").
+		Find(&rows).Error
+	if err != nil {
+		r.logger.Error("failed to find transcript segments", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	segments := make([]*transcript.Segment, 0, len(rows))
+	for _, row := range rows {
+		segments = append(segments, &transcript.Segment{
+			SubID:   row.SubID,
+			Index:   row.Seq,
+			Start:   time.Duration(row.StartMs) * time.Millisecond,
+			End:     time.Duration(row.EndMs) * time.Millisecond,
+			Speaker: row.Speaker,
+			Text:    row.Text,
+		})
+	}
+	return segments, nil
+}
+
+// Replace 替换课程的全部转写片段
+func (r *TranscriptRepository) Replace(ctx context.Context, subID int, segments []*transcript.Segment) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rows := make([]_struct.TranscriptSegment, 0, len(segments))
+	for i, segment := range segments {
+		rows = append(rows, _struct.TranscriptSegment{
+			SubID:   subID,
+			Seq:     i,
+			StartMs: segment.Start.Milliseconds(),
+			EndMs:   segment.End.Milliseconds(),
+			Speaker: segment.Speaker,
+			Text:    segment.Text,
+		})
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("sub_id = ?", subID).Delete(&_struct.TranscriptSegment{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(rows, transcriptBatchSize).Error
+	})
+	if err != nil {
+		r.logger.Error("failed to save transcript segments", logger.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	_struct "iwut-smartclass-backend/internal/database/struct"
+	"iwut-smartclass-backend/internal/domain/errors"
+	"iwut-smartclass-backend/internal/domain/usage"
+	"iwut-smartclass-backend/internal/infrastructure/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UsageRepository 用量台账与额度仓储实现
+type UsageRepository struct {
+	db     *gorm.DB
+	logger logger.Logger
+}
+
+// NewUsageRepository 创建用量仓储
+func NewUsageRepository(db *gorm.DB, logger logger.Logger) *UsageRepository {
+	return &UsageRepository{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Record 累加台账条目，同一账号、日期与模型合并为一行
+func (r *UsageRepository) Record(ctx context.Context, entry *usage.Entry) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	row := &_struct.UsageLedger{
+		Account:          entry.Account,
+		Day:              entry.Day,
+		Model:            entry.Model,
+		TenantID:         entry.TenantID,
+		PromptTokens:     entry.PromptTokens,
+		CompletionTokens: entry.CompletionTokens,
+		AsrSeconds:       entry.AsrSeconds,
+	}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"tenant_id":         entry.TenantID,
+			"prompt_tokens":     gorm.Expr("prompt_tokens + ?", entry.PromptTokens),
+			"completion_tokens": gorm.Expr("completion_tokens + ?", entry.CompletionTokens),
+			"asr_seconds":       gorm.Expr("asr_seconds + ?", entry.AsrSeconds),
+		}),
+	}).Create(row).Error
+	if err != nil {
+		r.logger.Error("failed to record usage", logger.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// ListByAccount 列出账号在 [fromDay, toDay] 内的台账条目
+func (r *UsageRepository) ListByAccount(ctx context.Context, account, fromDay, toDay string) ([]*usage.Entry, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var rows []_struct.UsageLedger
+	err := r.db.WithContext(ctx).
+		Where("account = ? AND day >= ? AND day <= ?", account, fromDay, toDay).
+		Order("day DESC, model").
+		Find(&rows).Error
+	if err != nil {
+		r.logger.Error("failed to list usage", logger.String("error", err.Error()))
+		return nil, err
+	}
+
+	entries := make([]*usage.Entry, 0, len(rows))
+	for i := range rows {
+		entries = append(entries, toUsageEntry(&rows[i]))
+	}
+	return entries, nil
+}
+
+// SumByAccount 合计账号在 [fromDay, toDay] 内的用量
+func (r *UsageRepository) SumByAccount(ctx context.Context, account, fromDay, toDay string) (usage.Totals, error) {
+	return r.sum(ctx, "account = ? AND day >= ? AND day <= ?", account, fromDay, toDay)
+}
+
+// SumByTenant 合计租户在 [fromDay, toDay] 内的用量
+func (r *UsageRepository) SumByTenant(ctx context.Context, tenantID int, fromDay, toDay string) (usage.Totals, error) {
+	return r.sum(ctx, "tenant_id = ? AND day >= ? AND day <= ?", tenantID, fromDay, toDay)
+}
+
+func (r *UsageRepository) sum(ctx context.Context, query string, args ...interface{}) (usage.Totals, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var totals struct {
+		PromptTokens     int64
+		CompletionTokens int64
+		AsrSeconds       int64
+	}
+	err := r.db.WithContext(ctx).Model(&_struct.UsageLedger{}).
+		Select("COALESCE(SUM(prompt_tokens), 0) AS prompt_tokens, COALESCE(SUM(completion_tokens), 0) AS completion_tokens, COALESCE(SUM(asr_seconds), 0) AS asr_seconds").
+		Where(query, args...).
+		Scan(&totals).Error
+	if err != nil {
+		r.logger.Error("failed to sum usage", logger.String("error", err.Error()))
+		return usage.Totals{}, err
+	}
+	return usage.Totals{
+		PromptTokens:     totals.PromptTokens,
+		CompletionTokens: totals.CompletionTokens,
+		AsrSeconds:       totals.AsrSeconds,
+	}, nil
+}
+
+// ListQuotas 列出额度，scope 为空时列出全部
+func (r *UsageRepository) ListQuotas(ctx context.Context, scope string) ([]*usage.Quota, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	query := r.db.WithContext(ctx).Order("scope, scope_value, period")
+	if scope != "" {
+		query = query.Where("scope = ?", scope)
+	}
+
+	var rows []_struct.UsageQuota
+	if err := query.Find(&rows).Error; err != nil {
+		r.logger.Error("failed to list usage quotas", logger.String("error", err.Error()))
+		return nil, err
+	}
+	return toUsageQuotas(rows), nil
+}
+
+// FindQuotas 查找作用域下各周期的额度
+func (r *UsageRepository) FindQuotas(ctx context.Context, scope, scopeValue string) ([]*usage.Quota, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var rows []_struct.UsageQuota
+	err := r.db.WithContext(ctx).
+		Where("scope = ? AND scope_value = ?", scope, scopeValue).
+		Order("period").
+		Find(&rows).Error
+	if err != nil {
+		r.logger.Error("failed to find usage quotas", logger.String("error", err.Error()))
+		return nil, err
+	}
+	return toUsageQuotas(rows), nil
+}
+
+// SaveQuota 创建或更新额度
+func (r *UsageRepository) SaveQuota(ctx context.Context, quota *usage.Quota) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	if quota.UpdatedAt.IsZero() {
+		quota.UpdatedAt = time.Now()
+	}
+	row := &_struct.UsageQuota{
+		Scope:         quota.Scope,
+		ScopeValue:    quota.ScopeValue,
+		Period:        quota.Period,
+		MaxTokens:     quota.MaxTokens,
+		MaxAsrSeconds: quota.MaxAsrSeconds,
+		UpdatedAt:     quota.UpdatedAt.Unix(),
+	}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"max_tokens", "max_asr_seconds", "updated_at"}),
+	}).Create(row).Error
+	if err != nil {
+		r.logger.Error("failed to save usage quota", logger.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// DeleteQuota 删除额度
+func (r *UsageRepository) DeleteQuota(ctx context.Context, scope, scopeValue, period string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).
+		Where("scope = ? AND scope_value = ? AND period = ?", scope, scopeValue, period).
+		Delete(&_struct.UsageQuota{})
+	if result.Error != nil {
+		r.logger.Error("failed to delete usage quota", logger.String("error", result.Error.Error()))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.NewNotFoundError("usage quota")
+	}
+	return nil
+}
+
+func toUsageEntry(row *_struct.UsageLedger) *usage.Entry {
+	return &usage.Entry{
+		Account:          row.Account,
+		TenantID:         row.TenantID,
+		Day:              row.Day,
+		Model:            row.Model,
+		PromptTokens:     row.PromptTokens,
+		CompletionTokens: row.CompletionTokens,
+		AsrSeconds:       row.AsrSeconds,
+	}
+}
+
+func toUsageQuotas(rows []_struct.UsageQuota) []*usage.Quota {
+	quotas := make([]*usage.Quota, 0, len(rows))
+	for _, row := range rows {
+		quotas = append(quotas, &usage.Quota{
+			Scope:         row.Scope,
+			ScopeValue:    row.ScopeValue,
+			Period:        row.Period,
+			MaxTokens:     row.MaxTokens,
+			MaxAsrSeconds: row.MaxAsrSeconds,
+			UpdatedAt:     time.Unix(row.UpdatedAt, 0),
+		})
+	}
+	return quotas
+}
@@ -0,0 +1,224 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"iwut-smartclass-backend/internal/database"
+	"iwut-smartclass-backend/internal/infrastructure/config"
+	"iwut-smartclass-backend/internal/infrastructure/logger"
+)
+
+// 限流范围
+const (
+	ScopeUser   = "user"
+	ScopeIP     = "ip"
+	ScopeGlobal = "global"
+)
+
+const (
+	// 清理已回满的桶的间隔
+	sweepInterval = 10 * time.Minute
+	// 单次存储操作的超时时间
+	storeTimeout = 3 * time.Second
+)
+
+// Limit 令牌桶参数，Rate 为每秒补充的令牌数，Rate 为 0 表示不限制
+type Limit struct {
+	Rate  float64
+	Burst float64
+}
+
+// PerHour 按每小时次数和突发容量创建令牌桶参数
+func PerHour(count, burst int) Limit {
+	return Limit{Rate: float64(count) / 3600, Burst: float64(burst)}
+}
+
+// Enabled 是否启用该限制
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst >= 1
+}
+
+// refillTime 空桶回满所需的时间
+func (l Limit) refillTime() time.Duration {
+	return time.Duration(l.Burst / l.Rate * float64(time.Second))
+}
+
+// Limits 各范围的限制
+type Limits struct {
+	User   Limit
+	IP     Limit
+	Global Limit
+}
+
+// Limiter 令牌桶限流器，依次检查用户、IP 和全局限制，任一限制不通过时退还已取出的令牌
+type Limiter struct {
+	store  Store
+	limits Limits
+	logger logger.Logger
+
+	sweepMu   sync.Mutex
+	lastSweep time.Time
+}
+
+// NewLimiter 创建限流器
+func NewLimiter(store Store, limits Limits, logger logger.Logger) *Limiter {
+	return &Limiter{store: store, limits: limits, logger: logger, lastSweep: time.Now()}
+}
+
+// NewLimiterFromConfig 根据配置创建限流器，所有限制都未启用时返回 nil
+func NewLimiterFromConfig(cfg *config.Config, logger logger.Logger) (*Limiter, error) {
+	limits := Limits{
+		User:   PerHour(cfg.RateLimitUserPerHour, cfg.RateLimitUserBurst),
+		IP:     PerHour(cfg.RateLimitIpPerHour, cfg.RateLimitIpBurst),
+		Global: PerHour(cfg.RateLimitGlobalPerHour, cfg.RateLimitGlobalBurst),
+	}
+	if !limits.User.Enabled() && !limits.IP.Enabled() && !limits.Global.Enabled() {
+		return nil, nil
+	}
+
+	var store Store
+	switch cfg.RateLimitBackend {
+	case "", "memory":
+		store = NewMemoryStore()
+	case "mysql":
+		db := database.GetDB()
+		if db == nil {
+			return nil, fmt.Errorf("database not initialized")
+		}
+		store = NewSQLStore(db)
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend: %s", cfg.RateLimitBackend)
+	}
+	return NewLimiter(store, limits, logger), nil
+}
+
+type rule struct {
+	scope string
+	key   string
+	limit Limit
+}
+
+// rules 返回适用于请求的限制
+func (l *Limiter) rules(account, ip string) []rule {
+	rules := make([]rule, 0, 3)
+	if l.limits.User.Enabled() && account != "" {
+		rules = append(rules, rule{ScopeUser, ScopeUser + ":" + account, l.limits.User})
+	}
+	if l.limits.IP.Enabled() && ip != "" {
+		rules = append(rules, rule{ScopeIP, ScopeIP + ":" + ip, l.limits.IP})
+	}
+	if l.limits.Global.Enabled() {
+		rules = append(rules, rule{ScopeGlobal, ScopeGlobal, l.limits.Global})
+	}
+	return rules
+}
+
+// Allow 为一次请求取出令牌。被拒绝时返回 false、触发限制的范围和建议的等待时间。
+// 存储出错时记录日志并放行，避免限流故障影响正常请求
+func (l *Limiter) Allow(ctx context.Context, account, ip string) (bool, string, time.Duration) {
+	rules := l.rules(account, ip)
+	now := time.Now()
+	l.maybeSweep(now)
+
+	for i, r := range rules {
+		allowed, wait, err := l.take(ctx, r, now)
+		if err != nil {
+			l.logger.Warn("rate limit check failed, allowing request",
+				logger.String("key", r.key),
+				logger.String("error", err.Error()),
+			)
+			continue
+		}
+		if !allowed {
+			for _, taken := range rules[:i] {
+				l.refund(ctx, taken, now)
+			}
+			return false, r.scope, wait
+		}
+	}
+	return true, "", 0
+}
+
+// Refund 退还 Allow 放行时取出的令牌，用于请求最终没有被受理的情况
+func (l *Limiter) Refund(ctx context.Context, account, ip string) {
+	now := time.Now()
+	for _, r := range l.rules(account, ip) {
+		l.refund(ctx, r, now)
+	}
+}
+
+// take 补充令牌后尝试取出一个，令牌不足时返回补足一个令牌需要等待的时间
+func (l *Limiter) take(ctx context.Context, r rule, now time.Time) (bool, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, storeTimeout)
+	defer cancel()
+
+	var allowed bool
+	var wait time.Duration
+	err := l.store.Update(ctx, r.key, func(bucket *Bucket) {
+		refill(bucket, r.limit, now)
+		if bucket.Tokens >= 1 {
+			bucket.Tokens--
+			allowed = true
+			return
+		}
+		wait = time.Duration(math.Ceil((1 - bucket.Tokens) / r.limit.Rate * float64(time.Second)))
+	})
+	return allowed, wait, err
+}
+
+// refund 退还一个令牌
+func (l *Limiter) refund(ctx context.Context, r rule, now time.Time) {
+	ctx, cancel := context.WithTimeout(ctx, storeTimeout)
+	defer cancel()
+
+	err := l.store.Update(ctx, r.key, func(bucket *Bucket) {
+		refill(bucket, r.limit, now)
+		bucket.Tokens = math.Min(r.limit.Burst, bucket.Tokens+1)
+	})
+	if err != nil {
+		l.logger.Warn("failed to refund rate limit token",
+			logger.String("key", r.key),
+			logger.String("error", err.Error()),
+		)
+	}
+}
+
+// refill 按经过的时间补充令牌，新建的桶是满的
+func refill(bucket *Bucket, limit Limit, now time.Time) {
+	if bucket.UpdatedAt.IsZero() {
+		bucket.Tokens = limit.Burst
+	} else if elapsed := now.Sub(bucket.UpdatedAt); elapsed > 0 {
+		bucket.Tokens = math.Min(limit.Burst, bucket.Tokens+elapsed.Seconds()*limit.Rate)
+	}
+	bucket.UpdatedAt = now
+}
+
+// maybeSweep 定期在后台删除已经回满的桶，删除后再次使用时按新建的满桶处理，结果不变
+func (l *Limiter) maybeSweep(now time.Time) {
+	l.sweepMu.Lock()
+	if now.Sub(l.lastSweep) < sweepInterval {
+		l.sweepMu.Unlock()
+		return
+	}
+	l.lastSweep = now
+	l.sweepMu.Unlock()
+
+	var longest time.Duration
+	for _, limit := range []Limit{l.limits.User, l.limits.IP, l.limits.Global} {
+		if limit.Enabled() && limit.refillTime() > longest {
+			longest = limit.refillTime()
+		}
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := l.store.Sweep(ctx, now.Add(-longest)); err != nil {
+			l.logger.Warn("failed to sweep rate limit buckets", logger.String("error", err.Error()))
+		}
+	}()
+}
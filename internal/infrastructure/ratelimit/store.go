@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	_struct "iwut-smartclass-backend/internal/database/struct"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Bucket 令牌桶状态，UpdatedAt 为零值表示桶尚不存在
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// Store 令牌桶存储
+type Store interface {
+	// Update 在锁内读取并修改桶，fn 返回后写回
+	Update(ctx context.Context, key string, fn func(bucket *Bucket)) error
+	// Sweep 删除 before 之后未再更新的桶
+	Sweep(ctx context.Context, before time.Time) error
+}
+
+// MemoryStore 内存存储，重启后清空，仅适用于单实例
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*Bucket
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*Bucket)}
+}
+
+func (s *MemoryStore) Update(ctx context.Context, key string, fn func(bucket *Bucket)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &Bucket{}
+		s.buckets[key] = bucket
+	}
+	fn(bucket)
+	return nil
+}
+
+func (s *MemoryStore) Sweep(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, bucket := range s.buckets {
+		if bucket.UpdatedAt.Before(before) {
+			delete(s.buckets, key)
+		}
+	}
+	return nil
+}
+
+// SQLStore MySQL 存储，桶保存在 rate_limit_bucket 表中，多个副本共享且重启后保留
+type SQLStore struct {
+	db *gorm.DB
+}
+
+// NewSQLStore 创建 MySQL 存储
+func NewSQLStore(db *gorm.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+func (s *SQLStore) Update(ctx context.Context, key string, fn func(bucket *Bucket)) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 先确保行存在，再加行锁读取，避免并发创建同一个桶
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&_struct.RateLimitBucket{BucketKey: key}).Error; err != nil {
+			return err
+		}
+		var row _struct.RateLimitBucket
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("bucket_key = ?", key).
+			First(&row).Error
+		if err != nil {
+			return err
+		}
+
+		bucket := &Bucket{Tokens: row.Tokens}
+		if row.UpdatedAt != 0 {
+			bucket.UpdatedAt = time.UnixMilli(row.UpdatedAt)
+		}
+		fn(bucket)
+
+		return tx.Model(&_struct.RateLimitBucket{}).
+			Where("bucket_key = ?", key).
+			Updates(map[string]interface{}{
+				"tokens":     bucket.Tokens,
+				"updated_at": bucket.UpdatedAt.UnixMilli(),
+			}).Error
+	})
+}
+
+func (s *SQLStore) Sweep(ctx context.Context, before time.Time) error {
+	return s.db.WithContext(ctx).
+		Where("updated_at < ?", before.UnixMilli()).
+		Delete(&_struct.RateLimitBucket{}).Error
+}
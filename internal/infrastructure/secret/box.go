@@ -0,0 +1,68 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	stdErrors "errors"
+	"fmt"
+	"strings"
+)
+
+// 密文前缀，便于以后更换算法
+const sealedPrefix = "v1:"
+
+// ErrInvalidSealed 密文格式错误或密钥不匹配
+var ErrInvalidSealed = stdErrors.New("invalid sealed value")
+
+// Box 使用 AES-256-GCM 加密需要落盘的敏感字段
+type Box struct {
+	aead cipher.AEAD
+}
+
+// NewBox 由配置的密钥创建加密器，密钥经 SHA-256 派生为 256 位
+func NewBox(key string) (*Box, error) {
+	if key == "" {
+		return nil, fmt.Errorf("empty key")
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Seal 加密明文，返回带版本前缀的 base64 文本
+func (b *Box) Seal(plaintext string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open 解密 Seal 生成的密文
+func (b *Box) Open(sealed string) (string, error) {
+	encoded, ok := strings.CutPrefix(sealed, sealedPrefix)
+	if !ok {
+		return "", ErrInvalidSealed
+	}
+	data, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(data) < b.aead.NonceSize() {
+		return "", ErrInvalidSealed
+	}
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrInvalidSealed
+	}
+	return string(plaintext), nil
+}
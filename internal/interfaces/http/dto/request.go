@@ -2,14 +2,29 @@ package dto
 
 // GetCourseRequest 获取课程请求
 type GetCourseRequest struct {
-	CourseName string `json:"course_name" binding:"required"`
-	Date       string `json:"date" binding:"required"`
-	Token      string `json:"token" binding:"required"`
+	CourseName   string `json:"course_name" binding:"required"`
+	Date         string `json:"date" binding:"required"`
+	Token        string `json:"token"`         // 兼容旧客户端，优先使用 Authorization 头
+	WithSegments bool   `json:"with_segments"` // 同时返回带时间戳的转写片段
 }
 
 // GenerateSummaryRequest 生成摘要请求
 type GenerateSummaryRequest struct {
 	SubID int    `json:"sub_id" binding:"required"`
-	Token string `json:"token" binding:"required"`
+	Token string `json:"token"` // 兼容旧客户端，优先使用 Authorization 头
 	Task  string `json:"task" binding:"required,oneof=new regenerate"`
 }
+
+// CreatePromptRequest 创建提示词模板请求
+type CreatePromptRequest struct {
+	Kind       string `json:"kind" binding:"required,oneof=summary section reduce"`
+	Scope      string `json:"scope" binding:"required,oneof=course_id course_name teacher tenant default"`
+	ScopeValue string `json:"scope_value"`
+	Content    string `json:"content" binding:"required"`
+}
+
+// SetQuotaRequest 设置用量额度请求，上限为 0 表示不限制该项
+type SetQuotaRequest struct {
+	MaxTokens     int64 `json:"max_tokens" binding:"min=0"`
+	MaxAsrSeconds int64 `json:"max_asr_seconds" binding:"min=0"`
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"iwut-smartclass-backend/internal/domain/errors"
+	"iwut-smartclass-backend/internal/infrastructure/external"
+	"iwut-smartclass-backend/internal/infrastructure/logger"
+	"iwut-smartclass-backend/internal/interfaces/http/dto"
+	"iwut-smartclass-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler 管理处理器
+type AdminHandler struct {
+	credentialPool *external.CredentialPool
+	logger         logger.Logger
+}
+
+// NewAdminHandler 创建管理处理器
+func NewAdminHandler(credentialPool *external.CredentialPool, logger logger.Logger) *AdminHandler {
+	return &AdminHandler{
+		credentialPool: credentialPool,
+		logger:         logger,
+	}
+}
+
+// ListDeadJobs 列出死信任务
+func (h *AdminHandler) ListDeadJobs(c *gin.Context) {
+	queue, ok := h.getQueue(c)
+	if !ok {
+		return
+	}
+
+	deadJobs, err := queue.ListDeadJobs()
+	if err != nil {
+		c.Error(errors.NewInternalError("failed to list dead jobs", err))
+		return
+	}
+
+	jobs := make([]map[string]interface{}, 0, len(deadJobs))
+	for _, dead := range deadJobs {
+		jobs = append(jobs, map[string]interface{}{
+			"id":       dead.ID,
+			"type":     dead.Type,
+			"attempts": len(dead.Attempts),
+			"dead_at":  formatJobTime(dead.DeadAt),
+		})
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse(map[string]interface{}{
+		"queue": c.Param("name"),
+		"jobs":  jobs,
+	}))
+}
+
+// GetDeadJob 获取死信任务详情
+func (h *AdminHandler) GetDeadJob(c *gin.Context) {
+	queue, ok := h.getQueue(c)
+	if !ok {
+		return
+	}
+
+	dead, err := queue.GetDeadJob(c.Param("id"))
+	if err != nil {
+		c.Error(deadJobError(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse(dead))
+}
+
+// RequeueDeadJob 重新入队死信任务
+func (h *AdminHandler) RequeueDeadJob(c *gin.Context) {
+	queue, ok := h.getQueue(c)
+	if !ok {
+		return
+	}
+
+	if err := queue.RequeueDeadJob(c.Param("id")); err != nil {
+		c.Error(deadJobError(err))
+		return
+	}
+
+	h.logger.Info("dead job requeued", logger.String("queue", c.Param("name")), logger.String("id", c.Param("id")))
+	c.JSON(http.StatusOK, dto.SuccessResponse(map[string]interface{}{
+		"id":    c.Param("id"),
+		"state": middleware.JobStateQueued,
+	}))
+}
+
+// DiscardDeadJob 丢弃死信任务
+func (h *AdminHandler) DiscardDeadJob(c *gin.Context) {
+	queue, ok := h.getQueue(c)
+	if !ok {
+		return
+	}
+
+	if err := queue.DiscardDeadJob(c.Param("id")); err != nil {
+		c.Error(deadJobError(err))
+		return
+	}
+
+	h.logger.Info("dead job discarded", logger.String("queue", c.Param("name")), logger.String("id", c.Param("id")))
+	c.JSON(http.StatusOK, dto.SuccessResponse(map[string]interface{}{
+		"id": c.Param("id"),
+	}))
+}
+
+func (h *AdminHandler) getQueue(c *gin.Context) (*middleware.WorkQueue, bool) {
+	queue := middleware.GetQueue(c.Param("name"))
+	if queue == nil {
+		c.Error(errors.NewNotFoundError("queue"))
+		return nil, false
+	}
+	return queue, true
+}
+
+func deadJobError(err error) error {
+	if stdErrors.Is(err, middleware.ErrDeadJobNotFound) {
+		return errors.NewNotFoundError("dead job")
+	}
+	return errors.NewInternalError("failed to process dead job", err)
+}
+
+// ListCredentials 列出腾讯云密钥的使用情况
+func (h *AdminHandler) ListCredentials(c *gin.Context) {
+	stats := h.credentialPool.Stats()
+	now := time.Now()
+
+	credentials := make([]map[string]interface{}, 0, len(stats))
+	for _, stat := range stats {
+		credentials = append(credentials, map[string]interface{}{
+			"index":         stat.Index,
+			"secret_id":     stat.SecretID,
+			"tasks":         stat.Tasks,
+			"audio_seconds": int64(stat.AudioDuration.Seconds()),
+			"failures":      stat.Failures,
+			"last_used_at":  formatJobTime(stat.LastUsedAt),
+			"last_error":    stat.LastError,
+			"benched_until": formatJobTime(stat.BenchedUntil),
+			"available":     !now.Before(stat.BenchedUntil),
+		})
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse(map[string]interface{}{
+		"strategy":    h.credentialPool.Strategy(),
+		"credentials": credentials,
+	}))
+}
+
+// ReleaseCredential 提前结束密钥的冷却
+func (h *AdminHandler) ReleaseCredential(c *gin.Context) {
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		c.Error(errors.NewValidationError("invalid index", err))
+		return
+	}
+
+	if err := h.credentialPool.Release(index); err != nil {
+		c.Error(err)
+		return
+	}
+
+	h.logger.Info("tencent credential released", logger.String("index", c.Param("index")))
+	c.JSON(http.StatusOK, dto.SuccessResponse(map[string]interface{}{
+		"index":     index,
+		"available": true,
+	}))
+}
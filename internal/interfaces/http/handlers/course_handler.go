@@ -1,17 +1,18 @@
 package handlers
 
 import (
-	"crypto/md5"
+	"context"
 	"fmt"
 	"net/http"
-	"net/url"
 	"strconv"
-	"time"
 
 	"iwut-smartclass-backend/internal/application/course"
+	appTranscript "iwut-smartclass-backend/internal/application/transcript"
 	domainCourse "iwut-smartclass-backend/internal/domain/course"
 	"iwut-smartclass-backend/internal/domain/errors"
 	"iwut-smartclass-backend/internal/domain/summary"
+	"iwut-smartclass-backend/internal/domain/transcript"
+	"iwut-smartclass-backend/internal/domain/user"
 	"iwut-smartclass-backend/internal/infrastructure/external"
 	"iwut-smartclass-backend/internal/infrastructure/logger"
 	"iwut-smartclass-backend/internal/interfaces/http/dto"
@@ -23,10 +24,11 @@ import (
 type CourseHandler struct {
 	courseService     *course.Service
 	summaryRepo       summary.Repository
-	userService       *external.UserService
+	transcriptRepo    transcript.Repository
 	scheduleService   *external.ScheduleService
 	liveCourseService *external.LiveCourseService
 	videoAuthService  *external.VideoAuthService
+	videoSigner       *external.VideoURLSigner
 	logger            logger.Logger
 }
 
@@ -34,19 +36,21 @@ type CourseHandler struct {
 func NewCourseHandler(
 	courseService *course.Service,
 	summaryRepo summary.Repository,
-	userService *external.UserService,
+	transcriptRepo transcript.Repository,
 	scheduleService *external.ScheduleService,
 	liveCourseService *external.LiveCourseService,
 	videoAuthService *external.VideoAuthService,
+	videoSigner *external.VideoURLSigner,
 	logger logger.Logger,
 ) *CourseHandler {
 	return &CourseHandler{
 		courseService:     courseService,
 		summaryRepo:       summaryRepo,
-		userService:       userService,
+		transcriptRepo:    transcriptRepo,
 		scheduleService:   scheduleService,
 		liveCourseService: liveCourseService,
 		videoAuthService:  videoAuthService,
+		videoSigner:       videoSigner,
 		logger:            logger,
 	}
 }
@@ -59,10 +63,17 @@ func (h *CourseHandler) GetCourse(c *gin.Context) {
 		return
 	}
 
+	// 请求的 ctx 贯穿所有外部调用，客户端断开时一并取消
 	ctx := c.Request.Context()
 
+	// 鉴权中间件解析的用户
+	userInfo, token, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
 	// 获取课程表
-	scheduleData, err := h.scheduleService.GetSchedule(req.Token, req.Date, req.CourseName)
+	scheduleData, err := h.scheduleService.GetSchedule(ctx, token, req.Date, req.CourseName)
 	if err != nil {
 		c.Error(err)
 		return
@@ -86,18 +97,11 @@ func (h *CourseHandler) GetCourse(c *gin.Context) {
 		return
 	}
 
-	// 获取用户信息
-	userInfo, err := h.userService.GetUserInfo(req.Token)
-	if err != nil {
-		c.Error(err)
-		return
-	}
-
 	// 尝试从数据库获取课程
 	courseEntity, err := h.courseService.GetCourse(ctx, subID)
 	if err != nil {
 		// 如果不存在，从外部服务获取
-		liveCourseData, err := h.liveCourseService.SearchLiveCourse(req.Token, subID, courseID)
+		liveCourseData, err := h.liveCourseService.SearchLiveCourse(ctx, token, subID, courseID)
 		if err != nil {
 			c.Error(err)
 			return
@@ -135,7 +139,7 @@ func (h *CourseHandler) GetCourse(c *gin.Context) {
 		}
 	} else if !courseEntity.HasVideo() {
 		// 如果视频为空，尝试再次获取
-		liveCourseData, err := h.liveCourseService.SearchLiveCourse(req.Token, subID, courseID)
+		liveCourseData, err := h.liveCourseService.SearchLiveCourse(ctx, token, subID, courseID)
 		if err != nil {
 			c.Error(err)
 			return
@@ -172,55 +176,27 @@ func (h *CourseHandler) GetCourse(c *gin.Context) {
 		"time":      courseEntity.Time,
 		"video":     courseEntity.Video,
 		"asr":       courseEntity.Asr,
-		"summary": map[string]string{
-			"status": courseEntity.SummaryStatus,
-			"data":   courseEntity.SummaryData,
-			"model":  courseEntity.Model,
-			"token":  fmt.Sprintf("%d", courseEntity.Token),
-		},
-	}
-
-	// 如果用户有摘要，使用用户的摘要
-	if len(userSummaries) > 0 {
-		status := courseEntity.SummaryStatus
-		if userSummaries[0].IsEmpty() {
-			if status == "" {
-				status = ""
-			}
-		} else {
-			status = "finished"
-		}
-		response["summary"] = map[string]string{
-			"status": status,
-			"data":   userSummaries[0].Summary,
-			"model":  userSummaries[0].Model,
-			"token":  fmt.Sprintf("%d", userSummaries[0].Token),
-		}
+		"summary":   summaryView(courseEntity, userSummaries),
 	}
 
-	// 添加视频认证
-	if courseEntity.HasVideo() {
-		authKey, err := h.videoAuthService.GetVideoAuthKey(req.Token, courseID, subID)
+	// 带时间戳的转写片段
+	if req.WithSegments {
+		segments, err := h.transcriptRepo.FindBySubID(ctx, subID)
 		if err != nil {
-			c.Error(err)
+			c.Error(errors.NewInternalError("failed to get transcript segments", err))
 			return
 		}
+		response["segments"] = segmentsResponse(segments)
+	}
 
-		// 生成视频认证参数
-		reversedPhone := userInfo.ReversePhone()
-		timestamp := time.Now().Unix()
-
-		// 计算MD5
-		parsedURL, err := url.Parse(courseEntity.Video)
+	// 添加视频认证
+	if courseEntity.HasVideo() {
+		signed, err := h.signVideo(ctx, token, userInfo, courseEntity)
 		if err != nil {
-			c.Error(errors.NewInternalError("failed to parse video URL", err))
+			c.Error(err)
 			return
 		}
-		md5Input := fmt.Sprintf("%s%d%d%s%d", parsedURL.Path, userInfo.ID, userInfo.TenantID, reversedPhone, timestamp)
-		md5Hash := fmt.Sprintf("%x", md5.Sum([]byte(md5Input)))
-
-		videoAuth := fmt.Sprintf("auth_key=%s&t=%d-%d-%s", authKey, userInfo.ID, timestamp, md5Hash)
-		response["video"] = fmt.Sprintf("%s?%s", courseEntity.Video, videoAuth)
+		response["video"] = signed.URL
 	}
 
 	h.logger.Info("get course success",
@@ -231,3 +207,185 @@ func (h *CourseHandler) GetCourse(c *gin.Context) {
 
 	c.JSON(http.StatusOK, dto.SuccessResponse(response))
 }
+
+// GetVideo 返回重新签名的视频地址，用于签名过期后刷新播放链接
+func (h *CourseHandler) GetVideo(c *gin.Context) {
+	subID, err := strconv.Atoi(c.Param("sub_id"))
+	if err != nil {
+		c.Error(errors.NewValidationError("invalid sub_id", err))
+		return
+	}
+
+	userInfo, token, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	courseEntity, err := h.courseService.GetCourse(ctx, subID)
+	if err != nil {
+		c.Error(errors.NewNotFoundError("course"))
+		return
+	}
+	if !courseEntity.HasVideo() {
+		c.Error(errors.NewNotFoundError("video"))
+		return
+	}
+
+	signed, err := h.signVideo(ctx, token, userInfo, courseEntity)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse(map[string]interface{}{
+		"sub_id":     subID,
+		"video":      signed.URL,
+		"expires_at": formatJobTime(signed.ExpiresAt),
+	}))
+}
+
+// signVideo 获取视频密钥并为用户签名视频地址
+func (h *CourseHandler) signVideo(ctx context.Context, token string, userInfo *user.User, courseEntity *domainCourse.Course) (external.SignedVideoURL, error) {
+	authKey, err := h.videoAuthService.GetVideoAuthKey(ctx, token, courseEntity.CourseID, courseEntity.SubID)
+	if err != nil {
+		return external.SignedVideoURL{}, err
+	}
+	return h.videoSigner.Sign(courseEntity.Video, userInfo, authKey)
+}
+
+// GetTranscript 导出课程转写文本，支持 srt、vtt、txt、json 格式；
+// 没有转写片段的旧数据只能导出按段落合并的全文
+func (h *CourseHandler) GetTranscript(c *gin.Context) {
+	subID, err := strconv.Atoi(c.Param("sub_id"))
+	if err != nil {
+		c.Error(errors.NewValidationError("invalid sub_id", err))
+		return
+	}
+	format := c.DefaultQuery("format", appTranscript.FormatJSON)
+	switch format {
+	case appTranscript.FormatSRT, appTranscript.FormatVTT, appTranscript.FormatText, appTranscript.FormatJSON:
+	default:
+		c.Error(errors.NewValidationError("format must be one of srt, vtt, txt, json", nil))
+		return
+	}
+
+	_, token, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	courseEntity, err := h.courseService.GetCourse(ctx, subID)
+	if err != nil {
+		c.Error(errors.NewNotFoundError("course"))
+		return
+	}
+	if err := authorizeCourse(ctx, h.liveCourseService, token, courseEntity); err != nil {
+		c.Error(err)
+		return
+	}
+	segments, err := h.transcriptRepo.FindBySubID(ctx, subID)
+	if err != nil {
+		c.Error(errors.NewInternalError("failed to get transcript segments", err))
+		return
+	}
+	if len(segments) == 0 && !courseEntity.HasAsr() {
+		c.Error(errors.NewNotFoundError("transcript"))
+		return
+	}
+
+	if format == appTranscript.FormatJSON {
+		c.JSON(http.StatusOK, dto.SuccessResponse(map[string]interface{}{
+			"sub_id":   subID,
+			"segments": segmentsResponse(segments),
+			"text":     courseEntity.Asr,
+		}))
+		return
+	}
+
+	// 旧数据没有时间信息，所有文本格式都降级为段落全文
+	if len(segments) == 0 {
+		format = appTranscript.FormatText
+		c.Header("X-Transcript-Fallback", "paragraphs")
+		h.writeTranscript(c, subID, format, "text/plain; charset=utf-8", appTranscript.RenderParagraphs(courseEntity.Asr))
+		return
+	}
+
+	switch format {
+	case appTranscript.FormatSRT:
+		h.writeTranscript(c, subID, format, "application/x-subrip; charset=utf-8", appTranscript.RenderSRT(segments))
+	case appTranscript.FormatVTT:
+		h.writeTranscript(c, subID, format, "text/vtt; charset=utf-8", appTranscript.RenderVTT(segments))
+	default:
+		h.writeTranscript(c, subID, format, "text/plain; charset=utf-8", appTranscript.RenderText(segments))
+	}
+}
+
+// authorizeCourse 通过用户令牌查询课程直播信息，查不到时说明用户无权查看该课程
+func authorizeCourse(ctx context.Context, liveCourseService *external.LiveCourseService, token string, courseEntity *domainCourse.Course) error {
+	_, err := liveCourseService.SearchLiveCourse(ctx, token, courseEntity.SubID, courseEntity.CourseID)
+	if err != nil {
+		if domainErr, ok := err.(*errors.DomainError); ok && domainErr.Type == errors.ErrorTypeNotFound {
+			return errors.NewForbiddenError("no access to this course")
+		}
+		return err
+	}
+	return nil
+}
+
+func (h *CourseHandler) writeTranscript(c *gin.Context, subID int, format, contentType, body string) {
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"%d.%s\"", subID, format))
+	c.Data(http.StatusOK, contentType, []byte(body))
+}
+
+func segmentsResponse(segments []*transcript.Segment) []map[string]interface{} {
+	items := make([]map[string]interface{}, 0, len(segments))
+	for _, segment := range segments {
+		items = append(items, map[string]interface{}{
+			"start_ms": segment.Start.Milliseconds(),
+			"end_ms":   segment.End.Milliseconds(),
+			"speaker":  segment.Speaker,
+			"text":     segment.Text,
+		})
+	}
+	return items
+}
+
+// summaryView 构建课程摘要响应，用户有摘要时使用用户最新的摘要
+func summaryView(courseEntity *domainCourse.Course, userSummaries []*summary.Summary) map[string]string {
+	if len(userSummaries) == 0 {
+		return map[string]string{
+			"status":         courseEntity.SummaryStatus,
+			"data":           courseEntity.SummaryData,
+			"partial":        courseEntity.SummaryPartial,
+			"model":          courseEntity.Model,
+			"prompt_version": courseEntity.PromptVersion,
+			"token":          fmt.Sprintf("%d", courseEntity.Token),
+		}
+	}
+
+	status := courseEntity.SummaryStatus
+	if !userSummaries[0].IsEmpty() {
+		status = "finished"
+	}
+	return map[string]string{
+		"status":         status,
+		"data":           userSummaries[0].Summary,
+		"partial":        userSummaries[0].Partial,
+		"model":          userSummaries[0].Model,
+		"prompt_version": userSummaries[0].PromptVersion,
+		"token":          fmt.Sprintf("%d", userSummaries[0].Token),
+	}
+}
+
+// currentUser 读取鉴权中间件写入的用户与令牌，缺失时报告未授权
+func currentUser(c *gin.Context) (*user.User, string, bool) {
+	ctx := c.Request.Context()
+	userInfo, ok := user.FromContext(ctx)
+	if !ok {
+		c.Error(errors.NewUnauthorizedError("missing token"))
+		return nil, "", false
+	}
+	return userInfo, user.TokenFromContext(ctx), true
+}
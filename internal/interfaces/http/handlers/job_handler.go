@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	stdErrors "errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"iwut-smartclass-backend/internal/domain/errors"
+	"iwut-smartclass-backend/internal/infrastructure/logger"
+	"iwut-smartclass-backend/internal/interfaces/http/dto"
+	"iwut-smartclass-backend/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobHandler 任务状态处理器
+type JobHandler struct {
+	queue  *middleware.WorkQueue
+	logger logger.Logger
+}
+
+// NewJobHandler 创建任务状态处理器
+func NewJobHandler(queue *middleware.WorkQueue, logger logger.Logger) *JobHandler {
+	return &JobHandler{
+		queue:  queue,
+		logger: logger,
+	}
+}
+
+// GetJob 获取单个任务状态，其他用户的任务按不存在处理
+func (h *JobHandler) GetJob(c *gin.Context) {
+	userInfo, _, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	status, ok := h.queue.GetJobStatus(c.Param("id"))
+	if !ok || status.Owner != userInfo.Account {
+		c.Error(errors.NewNotFoundError("job"))
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse(jobStatusView(status)))
+}
+
+// CancelJob 取消任务，只有提交任务的用户可以取消
+func (h *JobHandler) CancelJob(c *gin.Context) {
+	userInfo, _, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	id := c.Param("id")
+	status, ok := h.queue.GetJobStatus(id)
+	if !ok || status.IsFinished() {
+		c.Error(errors.NewNotFoundError("job"))
+		return
+	}
+	if status.Owner != userInfo.Account {
+		c.Error(errors.NewForbiddenError("job belongs to another user"))
+		return
+	}
+
+	if err := h.queue.CancelJob(c.Request.Context(), id); err != nil {
+		if stdErrors.Is(err, middleware.ErrJobNotFound) {
+			c.Error(errors.NewNotFoundError("job"))
+			return
+		}
+		c.Error(errors.NewInternalError("failed to cancel job", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse(map[string]interface{}{
+		"id":    id,
+		"state": middleware.JobStateCancelled,
+	}))
+}
+
+// ListJobs 按 sub_id 列出当前用户的任务状态
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	userInfo, _, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	subIDStr := c.Query("sub_id")
+	if subIDStr == "" {
+		c.Error(errors.NewValidationError("sub_id is required", nil))
+		return
+	}
+	subID, err := strconv.Atoi(subIDStr)
+	if err != nil {
+		c.Error(errors.NewValidationError("invalid sub_id", err))
+		return
+	}
+
+	statuses, err := h.queue.ListJobs(map[string]string{"sub_id": fmt.Sprintf("%d", subID)})
+	if err != nil {
+		c.Error(errors.NewInternalError("failed to list jobs", err))
+		return
+	}
+	jobs := make([]map[string]interface{}, 0, len(statuses))
+	for _, status := range statuses {
+		if status.Owner == userInfo.Account {
+			jobs = append(jobs, jobStatusView(status))
+		}
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse(map[string]interface{}{
+		"sub_id": subID,
+		"jobs":   jobs,
+	}))
+}
+
+// jobStatusView 构建任务状态响应
+func jobStatusView(status *middleware.JobStatus) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          status.ID,
+		"type":        status.Type,
+		"state":       status.State,
+		"labels":      status.Labels,
+		"position":    status.Position,
+		"enqueued_at": formatJobTime(status.EnqueuedAt),
+		"started_at":  formatJobTime(status.StartedAt),
+		"finished_at": formatJobTime(status.FinishedAt),
+		"duration_ms": status.Duration.Milliseconds(),
+		"last_error":  status.LastError,
+		"attempts":    status.Attempts,
+		"next_run_at": formatJobTime(status.NextRunAt),
+	}
+}
+
+func formatJobTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"iwut-smartclass-backend/internal/application/prompt"
+	"iwut-smartclass-backend/internal/domain/errors"
+	domainPrompt "iwut-smartclass-backend/internal/domain/prompt"
+	"iwut-smartclass-backend/internal/infrastructure/logger"
+	"iwut-smartclass-backend/internal/interfaces/http/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PromptHandler 提示词模板管理处理器
+type PromptHandler struct {
+	promptRepo domainPrompt.Repository
+	logger     logger.Logger
+}
+
+// NewPromptHandler 创建提示词模板管理处理器
+func NewPromptHandler(promptRepo domainPrompt.Repository, logger logger.Logger) *PromptHandler {
+	return &PromptHandler{
+		promptRepo: promptRepo,
+		logger:     logger,
+	}
+}
+
+// ListPrompts 列出提示词模板
+func (h *PromptHandler) ListPrompts(c *gin.Context) {
+	templates, err := h.promptRepo.List(c.Request.Context(), c.Query("kind"))
+	if err != nil {
+		c.Error(errors.NewInternalError("failed to list prompt templates", err))
+		return
+	}
+
+	items := make([]map[string]interface{}, 0, len(templates))
+	for _, t := range templates {
+		items = append(items, promptResponse(t))
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse(map[string]interface{}{
+		"embedded_version": prompt.EmbeddedVersion,
+		"templates":        items,
+	}))
+}
+
+// GetPrompt 获取提示词模板
+func (h *PromptHandler) GetPrompt(c *gin.Context) {
+	id, ok := promptID(c)
+	if !ok {
+		return
+	}
+
+	t, err := h.promptRepo.FindByID(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse(promptResponse(t)))
+}
+
+// CreatePrompt 保存提示词模板的新版本
+func (h *PromptHandler) CreatePrompt(c *gin.Context) {
+	var req dto.CreatePromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.NewValidationError("invalid request", err))
+		return
+	}
+	if req.Scope == domainPrompt.ScopeDefault {
+		req.ScopeValue = ""
+	} else if req.ScopeValue == "" {
+		c.Error(errors.NewValidationError("scope_value is required", nil))
+		return
+	}
+
+	t := &domainPrompt.Template{
+		Kind:       req.Kind,
+		Scope:      req.Scope,
+		ScopeValue: req.ScopeValue,
+		Content:    req.Content,
+		Enabled:    true,
+	}
+	if _, err := prompt.Parse(t); err != nil {
+		c.Error(err)
+		return
+	}
+	if err := h.promptRepo.Create(c.Request.Context(), t); err != nil {
+		c.Error(errors.NewInternalError("failed to create prompt template", err))
+		return
+	}
+
+	h.logger.Info("prompt template created", logger.String("template", t.Ref()))
+	c.JSON(http.StatusOK, dto.SuccessResponse(promptResponse(t)))
+}
+
+// DisablePrompt 停用提示词模板，同一作用域回退到上一个启用的版本
+func (h *PromptHandler) DisablePrompt(c *gin.Context) {
+	id, ok := promptID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.promptRepo.SetEnabled(c.Request.Context(), id, false); err != nil {
+		c.Error(err)
+		return
+	}
+
+	h.logger.Info("prompt template disabled", logger.String("id", c.Param("id")))
+	c.JSON(http.StatusOK, dto.SuccessResponse(map[string]interface{}{
+		"id":      id,
+		"enabled": false,
+	}))
+}
+
+func promptID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(errors.NewValidationError("invalid id", err))
+		return 0, false
+	}
+	return id, true
+}
+
+func promptResponse(t *domainPrompt.Template) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          t.ID,
+		"ref":         t.Ref(),
+		"kind":        t.Kind,
+		"scope":       t.Scope,
+		"scope_value": t.ScopeValue,
+		"version":     t.Version,
+		"content":     t.Content,
+		"enabled":     t.Enabled,
+		"created_at":  formatJobTime(t.CreatedAt),
+	}
+}
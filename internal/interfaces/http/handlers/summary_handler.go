@@ -1,15 +1,27 @@
 package handlers
 
 import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	appCourse "iwut-smartclass-backend/internal/application/course"
+	"iwut-smartclass-backend/internal/application/prompt"
 	appSummary "iwut-smartclass-backend/internal/application/summary"
+	appUsage "iwut-smartclass-backend/internal/application/usage"
 	"iwut-smartclass-backend/internal/domain/errors"
 	domainSummary "iwut-smartclass-backend/internal/domain/summary"
+	"iwut-smartclass-backend/internal/domain/transcript"
+	"iwut-smartclass-backend/internal/domain/user"
 	"iwut-smartclass-backend/internal/infrastructure/config"
+	"iwut-smartclass-backend/internal/infrastructure/events"
 	"iwut-smartclass-backend/internal/infrastructure/external"
 	"iwut-smartclass-backend/internal/infrastructure/logger"
+	"iwut-smartclass-backend/internal/infrastructure/ratelimit"
 	"iwut-smartclass-backend/internal/middleware"
 	"iwut-smartclass-backend/internal/interfaces/http/dto"
 
@@ -18,17 +30,23 @@ import (
 
 // SummaryHandler 摘要处理器
 type SummaryHandler struct {
-	logger           logger.Logger
-	queue            *middleware.WorkQueue
-	courseService    *appCourse.Service
-	summaryRepo      domainSummary.Repository
-	userService      *external.UserService
-	videoAuthService *external.VideoAuthService
-	ffmpegService    *external.FFmpegService
-	cosService       *external.COSService
-	asrService       *external.ASRService
-	openaiService    *external.OpenAIService
-	config           *config.Config
+	logger            logger.Logger
+	queue             *middleware.WorkQueue
+	courseService     *appCourse.Service
+	summaryRepo       domainSummary.Repository
+	transcriptRepo    transcript.Repository
+	userService       user.ExternalService
+	videoAuthService  *external.VideoAuthService
+	videoSigner       *external.VideoURLSigner
+	ffmpegService     *external.FFmpegService
+	cosService        *external.COSService
+	asrEngine         external.ASREngine
+	llmProvider       external.LLMProvider
+	prompts           *prompt.Registry
+	liveCourseService *external.LiveCourseService
+	usageService      *appUsage.Service
+	limiter           *ratelimit.Limiter
+	config            *config.Config
 }
 
 // NewSummaryHandler 创建摘要处理器
@@ -37,26 +55,38 @@ func NewSummaryHandler(
 	queue *middleware.WorkQueue,
 	courseService *appCourse.Service,
 	summaryRepo domainSummary.Repository,
-	userService *external.UserService,
+	transcriptRepo transcript.Repository,
+	userService user.ExternalService,
 	videoAuthService *external.VideoAuthService,
+	videoSigner *external.VideoURLSigner,
 	ffmpegService *external.FFmpegService,
 	cosService *external.COSService,
-	asrService *external.ASRService,
-	openaiService *external.OpenAIService,
+	asrEngine external.ASREngine,
+	llmProvider external.LLMProvider,
+	prompts *prompt.Registry,
+	liveCourseService *external.LiveCourseService,
+	usageService *appUsage.Service,
+	limiter *ratelimit.Limiter,
 	cfg *config.Config,
 ) *SummaryHandler {
 	return &SummaryHandler{
-		logger:           logger,
-		queue:            queue,
-		courseService:    courseService,
-		summaryRepo:      summaryRepo,
-		userService:      userService,
-		videoAuthService: videoAuthService,
-		ffmpegService:    ffmpegService,
-		cosService:       cosService,
-		asrService:       asrService,
-		openaiService:    openaiService,
-		config:           cfg,
+		logger:            logger,
+		queue:             queue,
+		courseService:     courseService,
+		summaryRepo:       summaryRepo,
+		transcriptRepo:    transcriptRepo,
+		userService:       userService,
+		videoAuthService:  videoAuthService,
+		videoSigner:       videoSigner,
+		ffmpegService:     ffmpegService,
+		cosService:        cosService,
+		asrEngine:         asrEngine,
+		llmProvider:       llmProvider,
+		prompts:           prompts,
+		liveCourseService: liveCourseService,
+		usageService:      usageService,
+		limiter:           limiter,
+		config:            cfg,
 	}
 }
 
@@ -82,9 +112,16 @@ func (h *SummaryHandler) GenerateSummary(c *gin.Context) {
 		return
 	}
 
+	// 鉴权中间件解析的用户
+	userInfo, token, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
 	// 创建摘要任务
 	job := appSummary.NewSummaryJob(
-		req.Token,
+		token,
+		userInfo,
 		req.SubID,
 		req.Task,
 		courseEntity.CourseID,
@@ -93,21 +130,204 @@ func (h *SummaryHandler) GenerateSummary(c *gin.Context) {
 		courseEntity.Asr,
 		h.courseService,
 		h.summaryRepo,
+		h.transcriptRepo,
 		h.userService,
 		h.videoAuthService,
+		h.videoSigner,
 		h.ffmpegService,
 		h.cosService,
-		h.asrService,
-		h.openaiService,
+		h.asrEngine,
+		h.llmProvider,
+		h.prompts,
+		h.usageService,
 		h.config,
 		h.logger,
 	)
 
-	// 添加到队列
-	h.queue.AddJob(job)
+	// 限制每个用户未完成的任务数量、用量额度和新建任务的频率，复用已有任务不受限制
+	limited := false
+	if status, ok := h.queue.GetJobStatus(job.GetID()); !ok || status.IsFinished() {
+		if limit := h.config.SummaryMaxUserJobs; limit > 0 {
+			count, err := h.queue.OwnerJobCount(userInfo.Account)
+			if err != nil {
+				c.Error(errors.NewServiceUnavailableError("failed to count pending summary jobs", err))
+				return
+			}
+			if count >= limit {
+				c.Error(errors.NewTooManyRequestsError(fmt.Sprintf("too many pending summary jobs, at most %d per user", limit)))
+				return
+			}
+		}
+		if err := h.usageService.CheckQuota(ctx, userInfo, time.Now()); err != nil {
+			c.Error(err)
+			return
+		}
+		if h.limiter != nil {
+			if allowed, scope, retryAfter := h.limiter.Allow(ctx, userInfo.Account, c.ClientIP()); !allowed {
+				h.logger.Warn("summary request rate limited",
+					logger.String("account", userInfo.Account),
+					logger.String("ip", c.ClientIP()),
+					logger.String("scope", scope),
+				)
+				c.Error(errors.NewRateLimitedError(scope, retryAfter))
+				return
+			}
+			limited = true
+		}
+	}
+
+	// 添加到队列，同一课程的相同任务正在执行时复用已有任务
+	status, created, err := h.queue.AddJob(ctx, job)
+	// 没有新建任务（入队失败或复用了已有任务）时退还令牌
+	if limited && (err != nil || !created) {
+		h.limiter.Refund(context.WithoutCancel(ctx), userInfo.Account, c.ClientIP())
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 复用已有任务时等待其结束并返回结果，其他用户无法通过任务接口查看该任务
+	if !created {
+		h.waitAttached(c, req.SubID, userInfo.Account, status)
+		return
+	}
 
 	c.JSON(http.StatusOK, dto.SuccessResponse(map[string]interface{}{
 		"sub_id":         req.SubID,
+		"job_id":         status.ID,
+		"job_state":      status.State,
+		"attached":       false,
 		"summary_status": "generating",
 	}))
 }
+
+// waitAttached 等待复用的任务结束，返回任务的最终状态与课程摘要
+func (h *SummaryHandler) waitAttached(c *gin.Context, subID int, account string, status *middleware.JobStatus) {
+	ctx := c.Request.Context()
+	final, err := h.queue.WaitJob(ctx, status.ID)
+	if err != nil && !stdErrors.Is(err, middleware.ErrJobNotFound) {
+		c.Error(errors.NewInternalError("failed to wait for job", err))
+		return
+	}
+
+	courseEntity, err := h.courseService.GetCourse(ctx, subID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	userSummaries, err := h.summaryRepo.FindBySubIDAndUser(ctx, subID, account)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	summaryResponse := summaryView(courseEntity, userSummaries)
+
+	// 任务已由其他实例结束并从队列删除时没有最终状态，以摘要状态为准
+	jobState := middleware.JobStateFailed
+	if final != nil {
+		jobState = final.State
+	} else if summaryResponse["status"] == "finished" {
+		jobState = middleware.JobStateSucceeded
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse(map[string]interface{}{
+		"sub_id":         subID,
+		"job_id":         status.ID,
+		"job_state":      jobState,
+		"attached":       true,
+		"summary_status": summaryResponse["status"],
+		"summary":        summaryResponse,
+	}))
+}
+
+// 事件流保活间隔
+const eventKeepAlive = 15 * time.Second
+
+// Events 以 Server-Sent Events 推送课程摘要进度，任务结束后关闭
+func (h *SummaryHandler) Events(c *gin.Context) {
+	subID, err := strconv.Atoi(c.Param("sub_id"))
+	if err != nil {
+		c.Error(errors.NewValidationError("invalid sub_id", err))
+		return
+	}
+
+	userInfo, token, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	// 只有能查看课程的用户可以订阅其进度
+	ctx := c.Request.Context()
+	courseEntity, err := h.courseService.GetCourse(ctx, subID)
+	if err != nil {
+		c.Error(errors.NewNotFoundError("course"))
+		return
+	}
+	if err := authorizeCourse(ctx, h.liveCourseService, token, courseEntity); err != nil {
+		c.Error(err)
+		return
+	}
+
+	topic := appSummary.EventTopic(subID)
+	stream, unsubscribe := events.GetBus().Subscribe(topic)
+	defer unsubscribe()
+
+	// 没有当前用户可见的进行中任务时返回空闲状态并结束
+	idle := false
+	if last, ok := events.GetBus().Last(topic); !ok || !last.VisibleTo(userInfo.Account) {
+		active, err := h.hasActiveJob(subID, userInfo.Account)
+		if err != nil {
+			c.Error(errors.NewInternalError("failed to list jobs", err))
+			return
+		}
+		idle = !active
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	if idle {
+		c.SSEvent("idle", events.Event{Type: "idle", Final: true, Time: time.Now()})
+		return
+	}
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event := <-stream:
+			// 其他用户重新生成的摘要不推送
+			if !event.VisibleTo(userInfo.Account) {
+				return true
+			}
+			c.SSEvent(event.Type, event)
+			return !event.Final
+		case <-keepAlive.C:
+			_, _ = io.WriteString(w, ": keep-alive\n\n")
+			return true
+		}
+	})
+}
+
+// hasActiveJob 检查课程是否有账号可见的未结束任务，其他用户的重新生成任务不计入
+func (h *SummaryHandler) hasActiveJob(subID int, account string) (bool, error) {
+	statuses, err := h.queue.ListJobs(map[string]string{"sub_id": strconv.Itoa(subID)})
+	if err != nil {
+		return false, err
+	}
+	for _, status := range statuses {
+		if status.Labels["task"] == "regenerate" && status.Owner != account {
+			continue
+		}
+		if !status.IsFinished() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	appUsage "iwut-smartclass-backend/internal/application/usage"
+	"iwut-smartclass-backend/internal/domain/errors"
+	"iwut-smartclass-backend/internal/domain/usage"
+	"iwut-smartclass-backend/internal/infrastructure/logger"
+	"iwut-smartclass-backend/internal/interfaces/http/dto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UsageHandler 用量与额度处理器
+type UsageHandler struct {
+	usageService *appUsage.Service
+	usageRepo    usage.Repository
+	logger       logger.Logger
+}
+
+// NewUsageHandler 创建用量与额度处理器
+func NewUsageHandler(usageService *appUsage.Service, usageRepo usage.Repository, logger logger.Logger) *UsageHandler {
+	return &UsageHandler{
+		usageService: usageService,
+		usageRepo:    usageRepo,
+		logger:       logger,
+	}
+}
+
+// GetMyUsage 返回当前用户今日与本月的用量、本月台账及适用的额度
+func (h *UsageHandler) GetMyUsage(c *gin.Context) {
+	userInfo, _, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.usageService.Report(c.Request.Context(), userInfo, time.Now())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	entries := make([]map[string]interface{}, 0, len(report.Entries))
+	for _, entry := range report.Entries {
+		item := totalsResponse(usage.Totals{
+			PromptTokens:     entry.PromptTokens,
+			CompletionTokens: entry.CompletionTokens,
+			AsrSeconds:       entry.AsrSeconds,
+		})
+		item["day"] = entry.Day
+		item["model"] = entry.Model
+		entries = append(entries, item)
+	}
+
+	quotas := make([]map[string]interface{}, 0, len(report.Quotas))
+	for _, status := range report.Quotas {
+		item := quotaResponse(status.Quota)
+		item["used"] = totalsResponse(status.Used)
+		item["exceeded"] = status.Exceeded()
+		item["reset_at"] = formatJobTime(status.ResetAt)
+		quotas = append(quotas, item)
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse(map[string]interface{}{
+		"account":   userInfo.Account,
+		"tenant_id": userInfo.TenantID,
+		"today":     totalsResponse(report.Day),
+		"month":     totalsResponse(report.Month),
+		"entries":   entries,
+		"quotas":    quotas,
+	}))
+}
+
+// ListQuotas 列出用量额度
+func (h *UsageHandler) ListQuotas(c *gin.Context) {
+	scope := c.Query("scope")
+	if scope != "" && !usage.IsValidScope(scope) {
+		c.Error(errors.NewValidationError("invalid scope", fmt.Errorf("scope must be %s or %s", usage.ScopeUser, usage.ScopeTenant)))
+		return
+	}
+
+	quotas, err := h.usageRepo.ListQuotas(c.Request.Context(), scope)
+	if err != nil {
+		c.Error(errors.NewInternalError("failed to list usage quotas", err))
+		return
+	}
+
+	items := make([]map[string]interface{}, 0, len(quotas))
+	for _, quota := range quotas {
+		items = append(items, quotaResponse(quota))
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponse(map[string]interface{}{
+		"quotas": items,
+	}))
+}
+
+// SetQuota 创建或更新账号或租户的日额度、月额度
+func (h *UsageHandler) SetQuota(c *gin.Context) {
+	quota, ok := quotaKey(c)
+	if !ok {
+		return
+	}
+	var req dto.SetQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.NewValidationError("invalid request", err))
+		return
+	}
+	quota.MaxTokens = req.MaxTokens
+	quota.MaxAsrSeconds = req.MaxAsrSeconds
+
+	if err := h.usageRepo.SaveQuota(c.Request.Context(), quota); err != nil {
+		c.Error(errors.NewInternalError("failed to save usage quota", err))
+		return
+	}
+
+	h.logger.Info("usage quota saved",
+		logger.String("scope", quota.Scope),
+		logger.String("scope_value", quota.ScopeValue),
+		logger.String("period", quota.Period),
+	)
+	c.JSON(http.StatusOK, dto.SuccessResponse(quotaResponse(quota)))
+}
+
+// DeleteQuota 删除用量额度
+func (h *UsageHandler) DeleteQuota(c *gin.Context) {
+	quota, ok := quotaKey(c)
+	if !ok {
+		return
+	}
+
+	if err := h.usageRepo.DeleteQuota(c.Request.Context(), quota.Scope, quota.ScopeValue, quota.Period); err != nil {
+		c.Error(err)
+		return
+	}
+
+	h.logger.Info("usage quota deleted",
+		logger.String("scope", quota.Scope),
+		logger.String("scope_value", quota.ScopeValue),
+		logger.String("period", quota.Period),
+	)
+	c.JSON(http.StatusOK, dto.SuccessResponse(map[string]interface{}{
+		"scope":       quota.Scope,
+		"scope_value": quota.ScopeValue,
+		"period":      quota.Period,
+	}))
+}
+
+// quotaKey 从路径参数读取额度的作用域、作用域值与周期
+func quotaKey(c *gin.Context) (*usage.Quota, bool) {
+	quota := &usage.Quota{
+		Scope:      c.Param("scope"),
+		ScopeValue: c.Param("value"),
+		Period:     c.Param("period"),
+	}
+	if !usage.IsValidScope(quota.Scope) {
+		c.Error(errors.NewValidationError("invalid scope", fmt.Errorf("scope must be %s or %s", usage.ScopeUser, usage.ScopeTenant)))
+		return nil, false
+	}
+	if !usage.IsValidPeriod(quota.Period) {
+		c.Error(errors.NewValidationError("invalid period", fmt.Errorf("period must be %s or %s", usage.PeriodDay, usage.PeriodMonth)))
+		return nil, false
+	}
+	return quota, true
+}
+
+func totalsResponse(t usage.Totals) map[string]interface{} {
+	return map[string]interface{}{
+		"prompt_tokens":     t.PromptTokens,
+		"completion_tokens": t.CompletionTokens,
+		"total_tokens":      t.Tokens(),
+		"asr_seconds":       t.AsrSeconds,
+	}
+}
+
+func quotaResponse(q *usage.Quota) map[string]interface{} {
+	return map[string]interface{}{
+		"scope":           q.Scope,
+		"scope_value":     q.ScopeValue,
+		"period":          q.Period,
+		"max_tokens":      q.MaxTokens,
+		"max_asr_seconds": q.MaxAsrSeconds,
+		"updated_at":      formatJobTime(q.UpdatedAt),
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"iwut-smartclass-backend/internal/domain/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth 管理接口鉴权中间件，未配置管理令牌时拒绝所有请求
+func AdminAuth(adminToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader("X-Admin-Token")
+		if adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+			c.Error(errors.NewForbiddenError("admin access denied"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"math"
 	"net/http"
+	"strconv"
 
 	"iwut-smartclass-backend/internal/domain/errors"
 	"iwut-smartclass-backend/internal/interfaces/http/dto"
@@ -20,6 +22,9 @@ func ErrorHandler() gin.HandlerFunc {
 
 			// 处理领域错误
 			if domainErr, ok := err.(*errors.DomainError); ok {
+				if domainErr.RetryAfter > 0 {
+					c.Header("Retry-After", strconv.Itoa(int(math.Ceil(domainErr.RetryAfter.Seconds()))))
+				}
 				c.JSON(domainErr.HTTPStatus(), dto.ErrorResponse(domainErr.HTTPStatus(), domainErr.Message))
 				return
 			}
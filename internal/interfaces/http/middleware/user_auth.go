@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"iwut-smartclass-backend/internal/domain/errors"
+	"iwut-smartclass-backend/internal/domain/user"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 为读取 token 字段而缓冲的请求体上限
+const maxTokenBodySize = 1 << 20
+
+// UserAuth 用户鉴权中间件，从 Authorization: Bearer 头读取令牌，没有时读取 JSON 请求体的 token 字段；
+// 解析出的用户与令牌写入请求的 ctx，处理器通过 user.FromContext 读取
+func UserAuth(userService user.ExternalService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := bearerToken(c)
+		if token == "" {
+			var err error
+			token, err = bodyToken(c)
+			if err != nil {
+				c.Error(errors.NewValidationError("invalid request", err))
+				c.Abort()
+				return
+			}
+		}
+		authenticate(c, userService, token)
+	}
+}
+
+// StreamAuth 事件流鉴权中间件，浏览器的 EventSource 无法设置请求头，
+// 没有 Authorization: Bearer 头时从 token 查询参数或 token Cookie 读取令牌
+func StreamAuth(userService user.ExternalService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := bearerToken(c)
+		if token == "" {
+			token = c.Query("token")
+		}
+		if token == "" {
+			token, _ = c.Cookie("token")
+		}
+		authenticate(c, userService, token)
+	}
+}
+
+// authenticate 解析令牌对应的用户并写入请求的 ctx
+func authenticate(c *gin.Context, userService user.ExternalService, token string) {
+	if token == "" {
+		c.Error(errors.NewUnauthorizedError("missing token"))
+		c.Abort()
+		return
+	}
+
+	ctx := c.Request.Context()
+	userInfo, err := userService.GetUserInfo(ctx, token)
+	if err != nil {
+		c.Error(err)
+		c.Abort()
+		return
+	}
+
+	c.Request = c.Request.WithContext(user.NewContext(ctx, userInfo, token))
+	c.Next()
+}
+
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) > len("Bearer ") && strings.EqualFold(header[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(header[len("Bearer "):])
+	}
+	return ""
+}
+
+// bodyToken 读取 JSON 请求体中的 token 字段，并恢复请求体供处理器绑定
+func bodyToken(c *gin.Context) (string, error) {
+	if c.Request.Body == nil || (c.ContentType() != "" && c.ContentType() != gin.MIMEJSON) {
+		return "", nil
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxTokenBodySize))
+	if err != nil {
+		return "", err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var payload struct {
+		Token string `json:"token"`
+	}
+	if len(body) == 0 || json.Unmarshal(body, &payload) != nil {
+		// 格式错误留给处理器报告
+		return "", nil
+	}
+	return payload.Token, nil
+}
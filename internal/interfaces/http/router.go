@@ -10,9 +10,16 @@ import (
 func SetupRouter(
 	courseHandler *handlers.CourseHandler,
 	summaryHandler *handlers.SummaryHandler,
+	jobHandler *handlers.JobHandler,
+	adminHandler *handlers.AdminHandler,
+	promptHandler *handlers.PromptHandler,
+	usageHandler *handlers.UsageHandler,
 	healthHandler *handlers.HealthHandler,
 	errorHandler gin.HandlerFunc,
 	loggerMiddleware gin.HandlerFunc,
+	adminAuth gin.HandlerFunc,
+	userAuth gin.HandlerFunc,
+	streamAuth gin.HandlerFunc,
 ) *gin.Engine {
 	router := gin.New()
 
@@ -25,8 +32,33 @@ func SetupRouter(
 	router.GET("/health", healthHandler.Health)
 
 	// 路由
-	router.POST("/getCourse", courseHandler.GetCourse)
-	router.POST("/generateSummary", summaryHandler.GenerateSummary)
+	router.POST("/getCourse", userAuth, courseHandler.GetCourse)
+	router.POST("/generateSummary", userAuth, summaryHandler.GenerateSummary)
+	router.GET("/summary/:sub_id/events", streamAuth, summaryHandler.Events)
+	router.GET("/course/:sub_id/transcript", userAuth, courseHandler.GetTranscript)
+	router.GET("/course/:sub_id/video", userAuth, courseHandler.GetVideo)
+	router.GET("/me/usage", userAuth, usageHandler.GetMyUsage)
+
+	// 任务状态
+	router.GET("/jobs", userAuth, jobHandler.ListJobs)
+	router.GET("/jobs/:id", userAuth, jobHandler.GetJob)
+	router.DELETE("/jobs/:id", userAuth, jobHandler.CancelJob)
+
+	// 管理接口
+	admin := router.Group("/admin", adminAuth)
+	admin.GET("/queues/:name/dead", adminHandler.ListDeadJobs)
+	admin.GET("/queues/:name/dead/:id", adminHandler.GetDeadJob)
+	admin.POST("/queues/:name/dead/:id/requeue", adminHandler.RequeueDeadJob)
+	admin.DELETE("/queues/:name/dead/:id", adminHandler.DiscardDeadJob)
+	admin.GET("/prompts", promptHandler.ListPrompts)
+	admin.GET("/prompts/:id", promptHandler.GetPrompt)
+	admin.POST("/prompts", promptHandler.CreatePrompt)
+	admin.DELETE("/prompts/:id", promptHandler.DisablePrompt)
+	admin.GET("/credentials", adminHandler.ListCredentials)
+	admin.POST("/credentials/:index/release", adminHandler.ReleaseCredential)
+	admin.GET("/quotas", usageHandler.ListQuotas)
+	admin.PUT("/quotas/:scope/:value/:period", usageHandler.SetQuota)
+	admin.DELETE("/quotas/:scope/:value/:period", usageHandler.DeleteQuota)
 
 	// 根路径
 	router.GET("/", func(c *gin.Context) {
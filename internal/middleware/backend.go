@@ -0,0 +1,133 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// QueueBackend 队列存储后端，负责任务的持久化与领取。
+// Extend、Update、Ack、Nack 与 Bury 只在任务仍由 record 的租约（LeaseOwner 与 LeaseToken）持有时生效，否则返回 ErrLeaseLost
+type QueueBackend interface {
+	// Enqueue 写入新任务，同ID任务未结束时返回 ErrJobExists。
+	// capacity 为等待执行的任务上限，0 表示不限制；检查与写入是原子的，已满时返回 ErrQueueFull
+	Enqueue(ctx context.Context, record *JobRecord, capacity int) error
+	// Lease 按优先级与虚拟时间领取一个可执行的任务并加租约，没有可执行任务时返回 nil
+	Lease(ctx context.Context, owner string, ttl time.Duration) (*JobRecord, error)
+	// Extend 延长租约，任务已删除时返回 ErrJobNotFound，租约已转给其他执行者时返回 ErrLeaseLost
+	Extend(ctx context.Context, record *JobRecord, ttl time.Duration) error
+	// Update 更新任务数据（检查点）
+	Update(ctx context.Context, record *JobRecord) error
+	// Ack 确认任务完成并删除
+	Ack(ctx context.Context, record *JobRecord) error
+	// Nack 归还任务，availableAt 之后可以再次领取
+	Nack(ctx context.Context, record *JobRecord, availableAt time.Time) error
+	// Remove 删除任意状态的任务并返回删除前的记录，不存在时返回 ErrJobNotFound
+	Remove(ctx context.Context, id string) (*JobRecord, error)
+	// List 列出未结束的任务
+	List(ctx context.Context) ([]*JobRecord, error)
+	// Bury 将任务移入死信
+	Bury(ctx context.Context, record *JobRecord) error
+	// ListDead 列出死信任务
+	ListDead(ctx context.Context) ([]*JobRecord, error)
+	// GetDead 获取死信任务
+	GetDead(ctx context.Context, id string) (*JobRecord, error)
+	// RemoveDead 删除死信任务
+	RemoveDead(ctx context.Context, id string) error
+}
+
+// JobRecord 队列后端中的任务记录
+type JobRecord struct {
+	ID          string            `json:"id"`
+	Type        string            `json:"type"`
+	Data        json.RawMessage   `json:"data"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Priority    int               `json:"priority"`
+	Owner       string            `json:"owner,omitempty"`
+	Attempts    []JobAttempt      `json:"attempts"`
+	State       JobState          `json:"state"`
+	EnqueuedAt  time.Time         `json:"enqueued_at"`
+	VirtualAt   time.Time         `json:"virtual_at"` // 公平调度的虚拟时间
+	AvailableAt time.Time         `json:"available_at"`
+	LeaseOwner  string            `json:"lease_owner,omitempty"`
+	LeaseToken  string            `json:"lease_token,omitempty"` // 每次领取生成的随机令牌，重启后持有者标识相同时仍能区分租约
+	LeaseUntil  time.Time         `json:"lease_until"`
+	DeadAt      time.Time         `json:"dead_at"`
+	Requeues    int               `json:"requeues,omitempty"` // 从死信重新入队的次数
+}
+
+var (
+	// ErrJobExists 同ID任务已在队列中
+	ErrJobExists = fmt.Errorf("job already exists")
+	// ErrQueueFull 等待执行的任务已达上限
+	ErrQueueFull = fmt.Errorf("queue is full")
+	// ErrDeadJobNotFound 死信任务不存在
+	ErrDeadJobNotFound = fmt.Errorf("dead job not found")
+	// ErrLeaseLost 任务已被删除或租约已转给其他执行者
+	ErrLeaseLost = fmt.Errorf("job lease lost")
+)
+
+// newLeaseToken 生成租约令牌
+func newLeaseToken() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// leasable 检查任务当前能否被领取（排队或等待重试且已到时间，或租约已过期）
+func (r *JobRecord) leasable(now time.Time) bool {
+	switch r.State {
+	case JobStateQueued, JobStateRetrying:
+		return !r.AvailableAt.After(now)
+	case JobStateRunning:
+		return r.LeaseUntil.Before(now)
+	default:
+		return false
+	}
+}
+
+// LastError 最近一次执行的错误
+func (r *JobRecord) LastError() string {
+	if len(r.Attempts) == 0 {
+		return ""
+	}
+	return r.Attempts[len(r.Attempts)-1].Error
+}
+
+func (r *JobRecord) clone() *JobRecord {
+	copied := *r
+	copied.Data = append(json.RawMessage(nil), r.Data...)
+	copied.Attempts = append([]JobAttempt(nil), r.Attempts...)
+	if r.Labels != nil {
+		copied.Labels = make(map[string]string, len(r.Labels))
+		for key, value := range r.Labels {
+			copied.Labels[key] = value
+		}
+	}
+	return &copied
+}
+
+// newJobRecord 根据任务创建新的记录
+func newJobRecord(job Job) (*JobRecord, error) {
+	data, err := json.Marshal(job.GetData())
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	priority, owner := jobPriority(job)
+	return &JobRecord{
+		ID:          job.GetID(),
+		Type:        job.GetType(),
+		Data:        data,
+		Labels:      jobLabels(job),
+		Priority:    priority,
+		Owner:       owner,
+		State:       JobStateQueued,
+		EnqueuedAt:  now,
+		VirtualAt:   now,
+		AvailableAt: now,
+	}, nil
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"time"
+)
+
+const (
+	defaultEnqueueTimeout = 5 * time.Second // 队列已满时入队的默认等待时间
+	defaultJobDuration    = 5 * time.Minute // 尚无执行记录时假定的任务耗时
+	durationSmoothing     = 0.2             // 平均耗时的指数平滑系数
+)
+
+// SetEnqueueTimeout 设置队列已满时入队的最长等待时间
+func (q *WorkQueue) SetEnqueueTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		q.enqueueTimeout = timeout
+	}
+}
+
+// recordDuration 更新任务平均耗时，调用方需持有 statusMutex
+func (q *WorkQueue) recordDuration(duration time.Duration) {
+	if q.avgDuration == 0 {
+		q.avgDuration = duration
+		return
+	}
+	q.avgDuration = time.Duration(float64(q.avgDuration)*(1-durationSmoothing) + float64(duration)*durationSmoothing)
+}
+
+// AverageDuration 获取最近任务的平均耗时
+func (q *WorkQueue) AverageDuration() time.Duration {
+	q.statusMutex.Lock()
+	defer q.statusMutex.Unlock()
+	if q.avgDuration == 0 {
+		return defaultJobDuration
+	}
+	return q.avgDuration
+}
+
+// EstimateWait 根据排队数量与平均耗时估算新任务开始执行前的等待时间
+func (q *WorkQueue) EstimateWait(queued int) time.Duration {
+	workers := q.workerCount
+	if workers < 1 {
+		workers = 1
+	}
+	rounds := (queued + workers - 1) / workers
+	if rounds < 1 {
+		rounds = 1
+	}
+	return time.Duration(rounds) * q.AverageDuration()
+}
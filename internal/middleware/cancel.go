@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	loggerPkg "iwut-smartclass-backend/internal/infrastructure/logger"
+)
+
+var (
+	// ErrJobCancelled 任务被取消
+	ErrJobCancelled = fmt.Errorf("job cancelled")
+	// ErrQueueShutdown 队列关闭时中断任务，任务保留在队列中等待下次启动恢复
+	ErrQueueShutdown = fmt.Errorf("queue shutting down")
+)
+
+// 取消后清理中间产物的最长时间
+const cleanupTimeout = time.Minute
+
+// Cleaner 可选接口，任务被取消后清理中间产物
+type Cleaner interface {
+	Cleanup(ctx context.Context)
+}
+
+// jobContext 创建任务执行上下文并登记取消函数，返回的函数用于注销
+func (q *WorkQueue) jobContext(id string) (context.Context, context.CancelCauseFunc, func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	q.statusMutex.Lock()
+	q.cancels[id] = cancel
+	q.statusMutex.Unlock()
+
+	return ctx, cancel, func() {
+		q.statusMutex.Lock()
+		delete(q.cancels, id)
+		q.statusMutex.Unlock()
+		cancel(nil)
+	}
+}
+
+// CancelJob 取消任务：排队中的任务直接移除，执行中的任务通过上下文中断。
+// 在其他实例执行的任务由该实例在下次续约失败时中断
+func (q *WorkQueue) CancelJob(ctx context.Context, id string) error {
+	record, err := q.backend.Remove(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	q.statusMutex.Lock()
+	cancel, running := q.cancels[id]
+	q.statusMutex.Unlock()
+
+	if running {
+		q.logger.Info("cancelling running job", loggerPkg.String("job", id))
+		cancel(ErrJobCancelled)
+		return nil
+	}
+	if record.State == JobStateRunning {
+		q.logger.Info("cancelling job running on another instance", loggerPkg.String("job", id), loggerPkg.String("owner", record.LeaseOwner))
+		return nil
+	}
+
+	q.logger.Info("cancelled queued job", loggerPkg.String("job", id))
+	q.finishCancelled(record, nil)
+	return nil
+}
+
+// interruptRunning 中断本实例所有执行中的任务
+func (q *WorkQueue) interruptRunning() {
+	q.statusMutex.Lock()
+	defer q.statusMutex.Unlock()
+	for id, cancel := range q.cancels {
+		q.logger.Warn("interrupting running job", loggerPkg.String("job", id))
+		cancel(ErrQueueShutdown)
+	}
+}
+
+// requeueInterrupted 将因关闭被中断的任务放回队列，不计入重试次数
+func (q *WorkQueue) requeueInterrupted(record *JobRecord) {
+	if err := q.backend.Nack(context.Background(), record, time.Now()); err != nil {
+		q.logger.Error("failed to requeue interrupted job", loggerPkg.String("job", record.ID), loggerPkg.String("error", err.Error()))
+		return
+	}
+	q.statusMutex.Lock()
+	delete(q.statuses, record.ID)
+	q.statusMutex.Unlock()
+	q.logger.Info("interrupted job kept for recovery", loggerPkg.String("job", record.ID))
+}
+
+// finishCancelled 清理已取消的任务并通知等待方，job 为 nil 时从记录重建
+func (q *WorkQueue) finishCancelled(record *JobRecord, job Job) {
+	if job == nil {
+		loaded, err := q.loadJob(record)
+		if err != nil {
+			q.logger.Warn("failed to load cancelled job", loggerPkg.String("job", record.ID), loggerPkg.String("error", err.Error()))
+		}
+		job = loaded
+	}
+	if cleaner, ok := job.(Cleaner); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+		cleaner.Cleanup(ctx)
+		cancel()
+	}
+	notifyState(job, JobStateCancelled, nil)
+
+	q.markCancelled(record)
+	q.forget(record.ID)
+	q.releaseJob(record.ID)
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"fmt"
+
+	loggerPkg "iwut-smartclass-backend/internal/infrastructure/logger"
+)
+
+// ListDeadJobs 列出死信任务，按进入死信的时间倒序
+func (q *WorkQueue) ListDeadJobs() ([]*JobRecord, error) {
+	return q.backend.ListDead(q.ctx)
+}
+
+// GetDeadJob 获取死信任务详情
+func (q *WorkQueue) GetDeadJob(id string) (*JobRecord, error) {
+	return q.backend.GetDead(q.ctx, id)
+}
+
+// RequeueDeadJob 将死信任务重新入队，重试次数重新计算，重新入队次数累加
+func (q *WorkQueue) RequeueDeadJob(id string) error {
+	dead, err := q.backend.GetDead(q.ctx, id)
+	if err != nil {
+		return err
+	}
+
+	job, err := q.loadJob(dead)
+	if err != nil {
+		return fmt.Errorf("failed to load job: %w", err)
+	}
+	if _, _, err := q.addJob(q.ctx, job, dead.Requeues+1); err != nil {
+		return err
+	}
+
+	q.logger.Info("requeued dead job", loggerPkg.String("id", id), loggerPkg.String("queue", q.name))
+	return q.backend.RemoveDead(q.ctx, id)
+}
+
+// DiscardDeadJob 删除死信任务
+func (q *WorkQueue) DiscardDeadJob(id string) error {
+	return q.backend.RemoveDead(q.ctx, id)
+}
@@ -0,0 +1,441 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	loggerPkg "iwut-smartclass-backend/internal/infrastructure/logger"
+)
+
+// FileBackend 基于本地 JSON 文件的队列后端，每个任务一个文件，只适用于单进程
+type FileBackend struct {
+	dir     string
+	records map[string]*JobRecord
+	mu      sync.Mutex
+	logger  loggerPkg.Logger
+}
+
+// jobEnvelope 任务持久化文件格式
+type jobEnvelope struct {
+	Type          string            `json:"type"`
+	Data          json.RawMessage   `json:"data"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	Priority      int               `json:"priority,omitempty"`
+	Owner         string            `json:"owner,omitempty"`
+	Attempts      []JobAttempt      `json:"attempts,omitempty"`
+	EnqueuedAt    *time.Time        `json:"enqueued_at,omitempty"`
+	VirtualAt     *time.Time        `json:"virtual_at,omitempty"`
+	NextAttemptAt *time.Time        `json:"next_attempt_at,omitempty"`
+}
+
+// NewFileBackend 创建文件队列后端并加载目录中已有的任务
+func NewFileBackend(dir string, logger loggerPkg.Logger) (*FileBackend, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create persistence dir: %w", err)
+	}
+
+	b := &FileBackend{
+		dir:     dir,
+		records: make(map[string]*JobRecord),
+		logger:  logger,
+	}
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// load 读取持久化目录，上次运行中的任务重新进入排队状态
+func (b *FileBackend) load() error {
+	files, err := os.ReadDir(b.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read persistence dir: %w", err)
+	}
+
+	for _, file := range files {
+		// 写入中途崩溃残留的临时文件，原任务文件仍完整
+		if !file.IsDir() && strings.Contains(file.Name(), tempFileMarker) {
+			_ = os.Remove(filepath.Join(b.dir, file.Name()))
+			continue
+		}
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+
+		path := filepath.Join(b.dir, file.Name())
+		content, err := os.ReadFile(path)
+		if err != nil {
+			b.logger.Error("failed to read job file", loggerPkg.String("file", file.Name()), loggerPkg.String("error", err.Error()))
+			continue
+		}
+
+		var envelope jobEnvelope
+		if err := json.Unmarshal(content, &envelope); err != nil {
+			b.logger.Error("failed to unmarshal job wrapper", loggerPkg.String("file", file.Name()), loggerPkg.String("error", err.Error()))
+			continue
+		}
+
+		record := &JobRecord{
+			ID:       strings.TrimSuffix(file.Name(), ".json"),
+			Type:     envelope.Type,
+			Data:     envelope.Data,
+			Labels:   envelope.Labels,
+			Priority: envelope.Priority,
+			Owner:    envelope.Owner,
+			Attempts: envelope.Attempts,
+			State:    JobStateQueued,
+		}
+		if envelope.EnqueuedAt != nil {
+			record.EnqueuedAt = *envelope.EnqueuedAt
+		} else if info, err := file.Info(); err == nil {
+			record.EnqueuedAt = info.ModTime()
+		}
+		if envelope.VirtualAt != nil {
+			record.VirtualAt = *envelope.VirtualAt
+		}
+		record.AvailableAt = record.EnqueuedAt
+		if envelope.NextAttemptAt != nil {
+			record.State = JobStateRetrying
+			record.AvailableAt = *envelope.NextAttemptAt
+		}
+		b.records[record.ID] = record
+	}
+	return nil
+}
+
+func (b *FileBackend) jobPath(id string) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%s.json", id))
+}
+
+func (b *FileBackend) deadDir() string {
+	return filepath.Join(b.dir, "dead")
+}
+
+func (b *FileBackend) deadJobPath(id string) (string, error) {
+	// 防止通过 ID 访问死信目录以外的文件
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return "", ErrDeadJobNotFound
+	}
+	return filepath.Join(b.deadDir(), fmt.Sprintf("%s.json", id)), nil
+}
+
+// write 写入任务文件，调用方需持有 mu
+func (b *FileBackend) write(record *JobRecord) error {
+	enqueuedAt := record.EnqueuedAt
+	envelope := jobEnvelope{
+		Type:       record.Type,
+		Data:       record.Data,
+		Labels:     record.Labels,
+		Priority:   record.Priority,
+		Owner:      record.Owner,
+		Attempts:   record.Attempts,
+		EnqueuedAt: &enqueuedAt,
+	}
+	if !record.VirtualAt.IsZero() {
+		virtualAt := record.VirtualAt
+		envelope.VirtualAt = &virtualAt
+	}
+	if record.State == JobStateRetrying {
+		nextAttemptAt := record.AvailableAt
+		envelope.NextAttemptAt = &nextAttemptAt
+	}
+	bytes, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(b.jobPath(record.ID), bytes, 0644)
+}
+
+// 临时文件名中的标记，加载时据此清理残留的临时文件
+const tempFileMarker = ".tmp-"
+
+// writeFileAtomic 先写入同目录的临时文件并落盘，再重命名覆盖目标文件，
+// 写入中途崩溃时目标文件保持原内容，不会留下截断的 JSON
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+tempFileMarker+"*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	// 同步目录，确保重命名本身已落盘
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+	return nil
+}
+
+// remove 删除任务文件，调用方需持有 mu
+func (b *FileBackend) remove(id string) error {
+	if err := os.Remove(b.jobPath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (b *FileBackend) Enqueue(ctx context.Context, record *JobRecord, capacity int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.records[record.ID]; exists {
+		return ErrJobExists
+	}
+	if capacity > 0 {
+		records := make([]*JobRecord, 0, len(b.records))
+		for _, stored := range b.records {
+			records = append(records, stored)
+		}
+		if queuedCount(records) >= capacity {
+			return ErrQueueFull
+		}
+	}
+	stored := record.clone()
+	if err := b.write(stored); err != nil {
+		return err
+	}
+	b.records[stored.ID] = stored
+	return nil
+}
+
+func (b *FileBackend) Lease(ctx context.Context, owner string, ttl time.Duration) (*JobRecord, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	var next *JobRecord
+	for _, record := range b.records {
+		if !record.leasable(now) {
+			continue
+		}
+		if next == nil || record.before(next) {
+			next = record
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+
+	next.State = JobStateRunning
+	next.LeaseOwner = owner
+	next.LeaseToken = newLeaseToken()
+	next.LeaseUntil = now.Add(ttl)
+	return next.clone(), nil
+}
+
+func (b *FileBackend) Extend(ctx context.Context, record *JobRecord, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.records[record.ID]; !ok {
+		return ErrJobNotFound
+	}
+	stored, err := b.leased(record)
+	if err != nil {
+		return err
+	}
+	stored.LeaseUntil = time.Now().Add(ttl)
+	return nil
+}
+
+// leased 返回仍由 record 的租约持有的记录，调用方需持有 mu
+func (b *FileBackend) leased(record *JobRecord) (*JobRecord, error) {
+	stored, ok := b.records[record.ID]
+	if !ok || stored.LeaseOwner != record.LeaseOwner || stored.LeaseToken != record.LeaseToken {
+		return nil, ErrLeaseLost
+	}
+	return stored, nil
+}
+
+func (b *FileBackend) Update(ctx context.Context, record *JobRecord) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stored, err := b.leased(record)
+	if err != nil {
+		return err
+	}
+	stored.Data = append(json.RawMessage(nil), record.Data...)
+	stored.Attempts = append([]JobAttempt(nil), record.Attempts...)
+	return b.write(stored)
+}
+
+func (b *FileBackend) Ack(ctx context.Context, record *JobRecord) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := b.leased(record); err != nil {
+		return err
+	}
+	delete(b.records, record.ID)
+	return b.remove(record.ID)
+}
+
+func (b *FileBackend) Remove(ctx context.Context, id string) (*JobRecord, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	record, ok := b.records[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	delete(b.records, id)
+	return record.clone(), b.remove(id)
+}
+
+func (b *FileBackend) Nack(ctx context.Context, record *JobRecord, availableAt time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stored, err := b.leased(record)
+	if err != nil {
+		return err
+	}
+	stored.Data = append(json.RawMessage(nil), record.Data...)
+	stored.Attempts = append([]JobAttempt(nil), record.Attempts...)
+	stored.State = JobStateRetrying
+	stored.AvailableAt = availableAt
+	stored.LeaseOwner = ""
+	stored.LeaseToken = ""
+	stored.LeaseUntil = time.Time{}
+	return b.write(stored)
+}
+
+func (b *FileBackend) List(ctx context.Context) ([]*JobRecord, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	records := make([]*JobRecord, 0, len(b.records))
+	for _, record := range b.records {
+		records = append(records, record.clone())
+	}
+	return records, nil
+}
+
+func (b *FileBackend) Bury(ctx context.Context, record *JobRecord) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := b.leased(record); err != nil {
+		return err
+	}
+	dead := record.clone()
+	dead.State = JobStateFailed
+	dead.LeaseOwner = ""
+	dead.LeaseToken = ""
+	dead.LeaseUntil = time.Time{}
+	dead.DeadAt = time.Now()
+	bytes, err := json.Marshal(dead)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(b.deadDir(), 0755); err != nil {
+		return err
+	}
+	path, err := b.deadJobPath(record.ID)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, bytes, 0644); err != nil {
+		return err
+	}
+
+	delete(b.records, record.ID)
+	return b.remove(record.ID)
+}
+
+func (b *FileBackend) ListDead(ctx context.Context) ([]*JobRecord, error) {
+	files, err := os.ReadDir(b.deadDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*JobRecord{}, nil
+		}
+		return nil, err
+	}
+
+	deadJobs := make([]*JobRecord, 0, len(files))
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		dead, err := b.GetDead(ctx, strings.TrimSuffix(file.Name(), ".json"))
+		if err != nil {
+			b.logger.Warn("failed to read dead job", loggerPkg.String("file", file.Name()), loggerPkg.String("error", err.Error()))
+			continue
+		}
+		deadJobs = append(deadJobs, dead)
+	}
+	sort.Slice(deadJobs, func(i, j int) bool {
+		return deadJobs[i].DeadAt.After(deadJobs[j].DeadAt)
+	})
+	return deadJobs, nil
+}
+
+func (b *FileBackend) GetDead(ctx context.Context, id string) (*JobRecord, error) {
+	path, err := b.deadJobPath(id)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrDeadJobNotFound
+		}
+		return nil, err
+	}
+
+	var dead JobRecord
+	if err := json.Unmarshal(content, &dead); err != nil {
+		return nil, err
+	}
+	if dead.ID == "" {
+		dead.ID = id
+	}
+	dead.State = JobStateFailed
+	return &dead, nil
+}
+
+func (b *FileBackend) RemoveDead(ctx context.Context, id string) error {
+	path, err := b.deadJobPath(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			return ErrDeadJobNotFound
+		}
+		return err
+	}
+	return nil
+}
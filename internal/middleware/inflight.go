@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"time"
+)
+
+// 等待其他实例执行的任务时的状态轮询间隔
+const waitPollInterval = 2 * time.Second
+
+// doneSignal 任务结束通知通道及其等待方数量
+type doneSignal struct {
+	ch      chan struct{}
+	waiters int
+}
+
+// watchJob 登记任务的等待方并返回结束通知，不存在时创建
+func (q *WorkQueue) watchJob(id string) *doneSignal {
+	q.statusMutex.Lock()
+	defer q.statusMutex.Unlock()
+
+	signal, ok := q.done[id]
+	if !ok {
+		signal = &doneSignal{ch: make(chan struct{})}
+		q.done[id] = signal
+	}
+	signal.waiters++
+	return signal
+}
+
+// unwatchJob 注销等待方，最后一个等待方离开时删除尚未关闭的通知，
+// 避免在任务已结束或不存在后创建的通知永远留在表中
+func (q *WorkQueue) unwatchJob(id string, signal *doneSignal) {
+	q.statusMutex.Lock()
+	defer q.statusMutex.Unlock()
+
+	signal.waiters--
+	if signal.waiters == 0 && q.done[id] == signal {
+		delete(q.done, id)
+	}
+}
+
+// releaseJob 任务最终结束（成功或进入死信）后通知等待方
+func (q *WorkQueue) releaseJob(id string) {
+	q.statusMutex.Lock()
+	defer q.statusMutex.Unlock()
+
+	if signal, ok := q.done[id]; ok {
+		close(signal.ch)
+		delete(q.done, id)
+	}
+}
+
+// WaitJob 等待任务结束并返回最终状态，重复提交的请求可借此获取已有任务的结果。
+// 其他实例执行完成的任务会从队列后端删除，本实例没有其状态，此时返回 ErrJobNotFound，
+// 调用方应以业务数据判断任务的结果
+func (q *WorkQueue) WaitJob(ctx context.Context, id string) (*JobStatus, error) {
+	signal := q.watchJob(id)
+	defer q.unwatchJob(id, signal)
+
+	ticker := time.NewTicker(waitPollInterval)
+	defer ticker.Stop()
+
+	for {
+		statuses, err := q.collectStatuses(ctx)
+		if err != nil {
+			return nil, err
+		}
+		status, ok := statuses[id]
+		if !ok {
+			return nil, ErrJobNotFound
+		}
+		if status.IsFinished() {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-signal.ch:
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	loggerPkg "iwut-smartclass-backend/internal/infrastructure/logger"
+)
+
+// JobState 任务生命周期状态
+type JobState string
+
+const (
+	JobStateQueued    JobState = "queued"    // 排队中
+	JobStateRunning   JobState = "running"   // 执行中
+	JobStateRetrying  JobState = "retrying"  // 失败后等待重试
+	JobStateSucceeded JobState = "succeeded" // 执行成功
+	JobStateFailed    JobState = "failed"    // 执行失败
+	JobStateCancelled JobState = "cancelled" // 已取消
+)
+
+// ErrJobNotFound 任务不存在
+var ErrJobNotFound = fmt.Errorf("job not found")
+
+// 已结束任务状态的保留时长，超时后从内存中清理
+const jobStatusRetention = time.Hour
+
+// Labeled 可选接口，任务提供用于查询过滤的标签
+type Labeled interface {
+	GetLabels() map[string]string
+}
+
+// JobStatus 任务状态快照
+type JobStatus struct {
+	ID         string
+	Type       string
+	State      JobState
+	Labels     map[string]string
+	Owner      string // 提交任务的用户
+	Position   int    // 在队列中的位置，从 1 开始，非排队状态为 0
+	EnqueuedAt time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Duration   time.Duration
+	LastError  string
+	Attempts   int       // 已执行次数
+	NextRunAt  time.Time // 下次重试时间，仅等待重试时有效
+}
+
+// IsFinished 检查任务是否已结束
+func (s *JobStatus) IsFinished() bool {
+	return s.State == JobStateSucceeded || s.State == JobStateFailed || s.State == JobStateCancelled
+}
+
+// matches 检查任务标签是否满足过滤条件
+func (s *JobStatus) matches(filter map[string]string) bool {
+	for key, value := range filter {
+		if s.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func jobLabels(job Job) map[string]string {
+	labels := make(map[string]string)
+	if l, ok := job.(Labeled); ok {
+		for key, value := range l.GetLabels() {
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+// markRunning 记录本实例开始执行任务
+func (q *WorkQueue) markRunning(record *JobRecord) {
+	q.statusMutex.Lock()
+	defer q.statusMutex.Unlock()
+
+	q.pruneStatuses()
+	q.statuses[record.ID] = &JobStatus{
+		ID:         record.ID,
+		Type:       record.Type,
+		State:      JobStateRunning,
+		Labels:     record.Labels,
+		Owner:      record.Owner,
+		EnqueuedAt: record.EnqueuedAt,
+		StartedAt:  time.Now(),
+		Attempts:   len(record.Attempts),
+	}
+}
+
+// markFinished 记录本实例执行任务结束，state 为执行后的状态（成功、失败或等待重试）
+func (q *WorkQueue) markFinished(record *JobRecord, state JobState, duration time.Duration) {
+	q.statusMutex.Lock()
+	defer q.statusMutex.Unlock()
+
+	status, ok := q.statuses[record.ID]
+	if !ok {
+		return
+	}
+	status.State = state
+	status.FinishedAt = time.Now()
+	status.Duration = duration
+	status.Attempts = len(record.Attempts)
+	status.LastError = record.LastError()
+	if state == JobStateRetrying {
+		status.NextRunAt = record.AvailableAt
+	}
+	if state == JobStateSucceeded {
+		q.recordDuration(duration)
+	}
+}
+
+// markCancelled 记录任务已取消
+func (q *WorkQueue) markCancelled(record *JobRecord) {
+	q.statusMutex.Lock()
+	defer q.statusMutex.Unlock()
+
+	status, ok := q.statuses[record.ID]
+	if !ok {
+		status = &JobStatus{
+			ID:         record.ID,
+			Type:       record.Type,
+			Labels:     record.Labels,
+			Owner:      record.Owner,
+			EnqueuedAt: record.EnqueuedAt,
+			Attempts:   len(record.Attempts),
+		}
+		q.statuses[record.ID] = status
+	}
+	status.State = JobStateCancelled
+	status.Position = 0
+	status.FinishedAt = time.Now()
+	if !status.StartedAt.IsZero() {
+		status.Duration = status.FinishedAt.Sub(status.StartedAt)
+	}
+}
+
+// pruneStatuses 清理过期的已结束任务，调用方需持有 statusMutex
+func (q *WorkQueue) pruneStatuses() {
+	for id, status := range q.statuses {
+		if status.IsFinished() && time.Since(status.FinishedAt) > jobStatusRetention {
+			delete(q.statuses, id)
+		}
+	}
+}
+
+// collectStatuses 合并队列后端中未结束的任务与本实例记录的执行信息
+func (q *WorkQueue) collectStatuses(ctx context.Context) (map[string]*JobStatus, error) {
+	records, err := q.backend.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	q.statusMutex.Lock()
+	defer q.statusMutex.Unlock()
+	q.pruneStatuses()
+
+	result := make(map[string]*JobStatus, len(records)+len(q.statuses))
+	for id, status := range q.statuses {
+		copied := *status
+		result[id] = &copied
+	}
+
+	for _, position := range q.positions(records) {
+		record := position.record
+		status := &JobStatus{
+			ID:         record.ID,
+			Type:       record.Type,
+			State:      record.State,
+			Labels:     record.Labels,
+			Owner:      record.Owner,
+			Position:   position.index,
+			EnqueuedAt: record.EnqueuedAt,
+			LastError:  record.LastError(),
+			Attempts:   len(record.Attempts),
+		}
+		if record.State == JobStateRetrying {
+			status.NextRunAt = record.AvailableAt
+		}
+		// 保留本实例记录的执行时间
+		if local, ok := result[record.ID]; ok && record.State == JobStateRunning {
+			status.StartedAt = local.StartedAt
+		}
+		result[record.ID] = status
+	}
+
+	for _, status := range result {
+		copied := make(map[string]string, len(status.Labels))
+		for key, value := range status.Labels {
+			copied[key] = value
+		}
+		status.Labels = copied
+	}
+	return result, nil
+}
+
+type recordPosition struct {
+	record *JobRecord
+	index  int
+}
+
+// positions 按调度顺序计算排队任务的位置，非排队状态的任务位置为 0
+func (q *WorkQueue) positions(records []*JobRecord) []recordPosition {
+	queued := make([]*JobRecord, 0, len(records))
+	for _, record := range records {
+		if record.State == JobStateQueued {
+			queued = append(queued, record)
+		}
+	}
+	sort.Slice(queued, func(i, j int) bool {
+		return queued[i].before(queued[j])
+	})
+
+	index := make(map[string]int, len(queued))
+	for i, record := range queued {
+		index[record.ID] = i + 1
+	}
+
+	result := make([]recordPosition, 0, len(records))
+	for _, record := range records {
+		result = append(result, recordPosition{record: record, index: index[record.ID]})
+	}
+	return result
+}
+
+// GetJobStatus 获取任务状态
+func (q *WorkQueue) GetJobStatus(id string) (*JobStatus, bool) {
+	statuses, err := q.collectStatuses(q.ctx)
+	if err != nil {
+		q.logger.Error("failed to list jobs", loggerPkg.String("error", err.Error()))
+		return nil, false
+	}
+	status, ok := statuses[id]
+	return status, ok
+}
+
+// ListJobs 按标签过滤列出任务状态，按入队时间排序；读取队列后端失败时返回错误
+func (q *WorkQueue) ListJobs(filter map[string]string) ([]*JobStatus, error) {
+	statuses, err := q.collectStatuses(q.ctx)
+	if err != nil {
+		q.logger.Error("failed to list jobs", loggerPkg.String("error", err.Error()))
+		return nil, err
+	}
+
+	result := make([]*JobStatus, 0)
+	for _, status := range statuses {
+		if status.matches(filter) {
+			result = append(result, status)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].EnqueuedAt.Before(result[j].EnqueuedAt)
+	})
+	return result, nil
+}
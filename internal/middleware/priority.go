@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"time"
+
+	loggerPkg "iwut-smartclass-backend/internal/infrastructure/logger"
+)
+
+// 任务优先级，数值越大越先执行
+const (
+	PriorityLow    = 0
+	PriorityNormal = 5
+	PriorityHigh   = 10
+)
+
+// 公平调度时同一用户每个未完成任务推迟的虚拟时间
+const fairShareQuantum = 5 * time.Minute
+
+// Prioritized 可选接口，任务提供优先级与所属用户，用于优先级调度与按用户公平调度
+type Prioritized interface {
+	GetPriority() int
+	GetOwner() string
+}
+
+// jobPriority 获取任务的优先级与所属用户，未实现 Prioritized 的任务为普通优先级
+func jobPriority(job Job) (int, string) {
+	if p, ok := job.(Prioritized); ok {
+		return p.GetPriority(), p.GetOwner()
+	}
+	return PriorityNormal, ""
+}
+
+// scheduleAt 调度排序使用的时间，旧记录没有虚拟时间时使用入队时间
+func (r *JobRecord) scheduleAt() time.Time {
+	if r.VirtualAt.IsZero() {
+		return r.EnqueuedAt
+	}
+	return r.VirtualAt
+}
+
+// before 检查任务是否应先于 other 执行：优先级高的优先，同优先级按虚拟时间排序
+func (r *JobRecord) before(other *JobRecord) bool {
+	if r.Priority != other.Priority {
+		return r.Priority > other.Priority
+	}
+	if !r.scheduleAt().Equal(other.scheduleAt()) {
+		return r.scheduleAt().Before(other.scheduleAt())
+	}
+	return r.EnqueuedAt.Before(other.EnqueuedAt)
+}
+
+// assignVirtualTime 为新任务计算虚拟时间：同一用户已有未完成任务时排在其最后一个任务之后，
+// 使任务多的用户不会占满队列
+func assignVirtualTime(record *JobRecord, outstanding []*JobRecord) {
+	virtualAt := record.EnqueuedAt
+	if record.Owner != "" {
+		for _, other := range outstanding {
+			if other.Owner != record.Owner {
+				continue
+			}
+			if next := other.scheduleAt().Add(fairShareQuantum); next.After(virtualAt) {
+				virtualAt = next
+			}
+		}
+	}
+	record.VirtualAt = virtualAt
+}
+
+// OwnerJobCount 统计用户未结束的任务数量，读取队列后端失败时返回错误
+func (q *WorkQueue) OwnerJobCount(owner string) (int, error) {
+	records, err := q.backend.List(q.ctx)
+	if err != nil {
+		q.logger.Error("failed to list jobs", loggerPkg.String("error", err.Error()))
+		return 0, err
+	}
+	count := 0
+	for _, record := range records {
+		if record.Owner == owner {
+			count++
+		}
+	}
+	return count, nil
+}
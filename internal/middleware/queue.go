@@ -3,7 +3,10 @@ package middleware
 import (
 	"context"
 	"encoding/json"
+	stdErrors "errors"
 	"fmt"
+	"iwut-smartclass-backend/internal/database"
+	"iwut-smartclass-backend/internal/domain/errors"
 	"iwut-smartclass-backend/internal/infrastructure/config"
 	loggerPkg "iwut-smartclass-backend/internal/infrastructure/logger"
 	"os"
@@ -13,24 +16,57 @@ import (
 )
 
 type Job interface {
-	Execute() error
+	Execute(ctx context.Context) error
 	GetID() string
 	GetData() interface{}
 	GetType() string
 }
 
+// Checkpointable 可选接口，任务完成阶段后通过回调持久化中间状态，恢复时从检查点继续
+type Checkpointable interface {
+	SetCheckpoint(save func() error)
+}
+
+// StateObserver 可选接口，任务进入排队、执行、等待重试或结束状态时收到通知
+type StateObserver interface {
+	OnStateChange(state JobState, err error)
+}
+
+func notifyState(job Job, state JobState, err error) {
+	if observer, ok := job.(StateObserver); ok {
+		observer.OnStateChange(state, err)
+	}
+}
+
+const (
+	leaseTTL          = 2 * time.Minute  // 任务租约时长
+	heartbeatInterval = 30 * time.Second // 执行中任务的租约续期间隔
+	pollInterval      = time.Second      // 空闲 Worker 轮询后端的间隔
+)
+
 type WorkQueue struct {
 	name           string        // 队列名称
-	jobQueue       chan Job      // 任务通道
+	backend        QueueBackend  // 存储后端
+	owner          string        // 租约持有者标识
+	queueSize      int           // 排队任务上限
+	enqueueTimeout time.Duration // 队列已满时入队的最长等待时间
+	avgDuration    time.Duration // 任务平均耗时，用于估算等待时间
 	workerPool     chan struct{} // 控制 Worker 并发量的信号
 	workerCount    int           // Worker 数量
 	wg             sync.WaitGroup
 	ctx            context.Context
 	cancelFunc     context.CancelFunc
-	shutdownChan   chan struct{}        // 关闭信号通道
-	persistenceDir string               // 持久化目录
-	jobLoaders     map[string]JobLoader // Job 加载器
-	logger         loggerPkg.Logger     // 日志
+	shutdownChan   chan struct{}                      // 关闭信号通道
+	wakeup         chan struct{}                      // 新任务通知
+	jobLoaders     map[string]JobLoader               // Job 加载器
+	logger         loggerPkg.Logger                   // 日志
+	retryPolicies  map[string]RetryPolicy             // 按任务类型的重试策略
+	config         *config.Config                     // 加载任务时使用的配置
+	live           map[string]Job                     // 本实例提交的任务，领取时优先复用
+	statuses       map[string]*JobStatus              // 本实例执行过的任务状态
+	done           map[string]*doneSignal             // 任务结束通知
+	cancels        map[string]context.CancelCauseFunc // 执行中任务的取消函数
+	statusMutex    sync.Mutex
 }
 
 type JobLoader func([]byte, *config.Config, loggerPkg.Logger) (Job, error)
@@ -41,7 +77,7 @@ var (
 	globalLoaders = make(map[string]JobLoader)
 )
 
-func NewWorkQueue(name string, workerCount int, queueSize int, logger loggerPkg.Logger) *WorkQueue {
+func NewWorkQueue(name string, workerCount int, queueSize int, backend QueueBackend, logger loggerPkg.Logger) *WorkQueue {
 	queueMutex.Lock()
 	defer queueMutex.Unlock()
 
@@ -49,24 +85,27 @@ func NewWorkQueue(name string, workerCount int, queueSize int, logger loggerPkg.
 		return q
 	}
 
-	// 创建持久化目录
-	persistenceDir := filepath.Join("data", "queues", name)
-	if err := os.MkdirAll(persistenceDir, 0755); err != nil {
-		logger.Error("failed to create persistence dir", loggerPkg.String("error", fmt.Sprintf("%v", err)))
-	}
-
+	hostname, _ := os.Hostname()
 	ctx, cancel := context.WithCancel(context.Background())
 	queue := &WorkQueue{
 		name:           name,
-		jobQueue:       make(chan Job, queueSize),
+		backend:        backend,
+		owner:          fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		queueSize:      queueSize,
+		enqueueTimeout: defaultEnqueueTimeout,
 		workerPool:     make(chan struct{}, workerCount),
 		workerCount:    workerCount,
 		ctx:            ctx,
 		cancelFunc:     cancel,
 		shutdownChan:   make(chan struct{}),
-		persistenceDir: persistenceDir,
+		wakeup:         make(chan struct{}, 1),
 		jobLoaders:     make(map[string]JobLoader),
 		logger:         logger,
+		retryPolicies:  make(map[string]RetryPolicy),
+		live:           make(map[string]Job),
+		statuses:       make(map[string]*JobStatus),
+		done:           make(map[string]*doneSignal),
+		cancels:        make(map[string]context.CancelCauseFunc),
 	}
 
 	// 注册全局 Loaders
@@ -74,6 +113,11 @@ func NewWorkQueue(name string, workerCount int, queueSize int, logger loggerPkg.
 		queue.jobLoaders[jobType] = loader
 	}
 
+	// 注册全局重试策略
+	for jobType, policy := range globalRetryPolicies {
+		queue.retryPolicies[jobType] = policy
+	}
+
 	queues[name] = queue
 	logger.Info("created work queue", loggerPkg.String("name", name), loggerPkg.String("workers", fmt.Sprintf("%d", workerCount)))
 
@@ -90,61 +134,43 @@ func (q *WorkQueue) RegisterLoader(jobType string, loader JobLoader) {
 
 func (q *WorkQueue) Start(cfg *config.Config) {
 	q.logger.Info("starting work queue", loggerPkg.String("name", q.name))
+	q.config = cfg
+
+	// 恢复未完成的任务
+	q.Recover(cfg)
 
 	for i := 0; i < q.workerCount; i++ {
 		q.wg.Add(1)
 		go q.Worker(i)
 	}
-
-	// 恢复未完成的任务
-	go q.Recover(cfg)
 }
 
+// Recover 检查后端中未完成的任务，任务ID规则变化时迁移记录
 func (q *WorkQueue) Recover(cfg *config.Config) {
-	files, err := os.ReadDir(q.persistenceDir)
+	records, err := q.backend.List(q.ctx)
 	if err != nil {
-		q.logger.Error("failed to read persistence dir", loggerPkg.String("error", err.Error()))
+		q.logger.Error("failed to list persisted jobs", loggerPkg.String("error", err.Error()))
 		return
 	}
 
 	count := 0
-	for _, file := range files {
-		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
-			continue
-		}
-
-		content, err := os.ReadFile(filepath.Join(q.persistenceDir, file.Name()))
-		if err != nil {
-			q.logger.Error("failed to read job file", loggerPkg.String("file", file.Name()), loggerPkg.String("error", err.Error()))
-			continue
-		}
-
-		var wrapper struct {
-			Type string          `json:"type"`
-			Data json.RawMessage `json:"data"`
-		}
-		if err := json.Unmarshal(content, &wrapper); err != nil {
-			q.logger.Error("failed to unmarshal job wrapper", loggerPkg.String("file", file.Name()), loggerPkg.String("error", err.Error()))
-			continue
-		}
-
-		loader, ok := q.jobLoaders[wrapper.Type]
-		if !ok {
-			q.logger.Warn("no loader found for job type", loggerPkg.String("type", wrapper.Type))
+	for _, record := range records {
+		// 其他实例正在执行的任务不做处理
+		if record.State == JobStateRunning {
 			continue
 		}
 
-		job, err := loader(wrapper.Data, cfg, q.logger)
+		job, err := q.loadJob(record)
 		if err != nil {
-			q.logger.Error("failed to load job", loggerPkg.String("file", file.Name()), loggerPkg.String("error", err.Error()))
+			q.logger.Error("failed to load job", loggerPkg.String("job", record.ID), loggerPkg.String("error", err.Error()))
 			continue
 		}
 
-		if err := q.migrateJobFile(file.Name(), job); err != nil {
-			q.logger.Warn("failed to migrate job file", loggerPkg.String("file", file.Name()), loggerPkg.String("error", err.Error()))
+		if job.GetID() != record.ID {
+			if err := q.migrateRecord(record, job); err != nil {
+				q.logger.Warn("failed to migrate job record", loggerPkg.String("job", record.ID), loggerPkg.String("error", err.Error()))
+			}
 		}
-
-		q.jobQueue <- job
 		count++
 	}
 	if count > 0 {
@@ -152,129 +178,415 @@ func (q *WorkQueue) Recover(cfg *config.Config) {
 	}
 }
 
-func (q *WorkQueue) migrateJobFile(oldFileName string, job Job) error {
-	newFileName := fmt.Sprintf("%s.json", job.GetID())
-	if oldFileName == newFileName {
-		return nil
+// migrateRecord 以新的任务ID重新写入记录并删除旧记录
+func (q *WorkQueue) migrateRecord(record *JobRecord, job Job) error {
+	migrated := record.clone()
+	migrated.ID = job.GetID()
+	migrated.Labels = jobLabels(job)
+	migrated.Priority, migrated.Owner = jobPriority(job)
+	if err := q.backend.Enqueue(q.ctx, migrated, 0); err != nil && !stdErrors.Is(err, ErrJobExists) {
+		return err
 	}
+	return q.backend.Ack(q.ctx, record)
+}
 
-	if err := q.saveJob(job); err != nil {
-		return err
+// loadJob 使用注册的加载器从记录重建任务
+func (q *WorkQueue) loadJob(record *JobRecord) (Job, error) {
+	loader, ok := q.jobLoaders[record.Type]
+	if !ok {
+		return nil, fmt.Errorf("no loader found for job type: %s", record.Type)
 	}
+	return loader(record.Data, q.config, q.logger)
+}
 
-	oldPath := filepath.Join(q.persistenceDir, oldFileName)
-	if err := os.Remove(oldPath); err != nil {
-		return err
+// jobFor 优先复用本实例提交的任务对象，否则从记录重建
+func (q *WorkQueue) jobFor(record *JobRecord) (Job, error) {
+	q.statusMutex.Lock()
+	job, ok := q.live[record.ID]
+	q.statusMutex.Unlock()
+	if ok {
+		return job, nil
 	}
-	return nil
+	return q.loadJob(record)
+}
+
+func (q *WorkQueue) remember(job Job) {
+	q.statusMutex.Lock()
+	defer q.statusMutex.Unlock()
+	q.live[job.GetID()] = job
+}
+
+func (q *WorkQueue) forget(id string) {
+	q.statusMutex.Lock()
+	defer q.statusMutex.Unlock()
+	delete(q.live, id)
 }
 
-func (q *WorkQueue) saveJob(job Job) error {
-	data := map[string]interface{}{
-		"type": job.GetType(),
-		"data": job.GetData(),
+// notify 唤醒一个空闲 Worker
+func (q *WorkQueue) notify() {
+	select {
+	case q.wakeup <- struct{}{}:
+	default:
 	}
-	bytes, err := json.Marshal(data)
+}
+
+// checkpoint 持久化任务的最新数据
+func (q *WorkQueue) checkpoint(record *JobRecord, job Job) error {
+	data, err := json.Marshal(job.GetData())
 	if err != nil {
 		return err
 	}
-	filename := filepath.Join(q.persistenceDir, fmt.Sprintf("%s.json", job.GetID()))
-	return os.WriteFile(filename, bytes, 0644)
+	record.Data = data
+	return q.backend.Update(context.Background(), record)
 }
 
-func (q *WorkQueue) deleteJob(job Job) error {
-	filename := filepath.Join(q.persistenceDir, fmt.Sprintf("%s.json", job.GetID()))
-	if err := os.Remove(filename); err != nil {
-		legacyPattern := filepath.Join(q.persistenceDir, fmt.Sprintf("%s-*.json", job.GetID()))
-		matches, _ := filepath.Glob(legacyPattern)
-		if len(matches) == 0 {
-			return err
-		}
-		var lastErr error
-		for _, path := range matches {
-			if rmErr := os.Remove(path); rmErr != nil {
-				lastErr = rmErr
+// heartbeat 定期续约执行中的任务，任务记录已被删除时取消任务，返回停止函数
+func (q *WorkQueue) heartbeat(record *JobRecord, cancel context.CancelCauseFunc) func() {
+	id := record.ID
+	lease := &JobRecord{ID: record.ID, LeaseOwner: record.LeaseOwner, LeaseToken: record.LeaseToken}
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				err := q.backend.Extend(context.Background(), lease, leaseTTL)
+				if stdErrors.Is(err, ErrJobNotFound) {
+					q.logger.Info("job removed from queue, cancelling", loggerPkg.String("job", id))
+					cancel(ErrJobCancelled)
+					return
+				}
+				if stdErrors.Is(err, ErrLeaseLost) {
+					q.logger.Warn("job lease lost, stopping", loggerPkg.String("job", id))
+					cancel(ErrLeaseLost)
+					return
+				}
+				if err != nil {
+					q.logger.Warn("failed to extend job lease", loggerPkg.String("job", id), loggerPkg.String("error", err.Error()))
+				}
 			}
 		}
-		return lastErr
-	}
-	return nil
+	}()
+	return func() { close(stop) }
 }
 
 func (q *WorkQueue) Worker(id int) {
 	defer q.wg.Done()
 	workerName := fmt.Sprintf("%s-worker-%d", q.name, id)
+	// 每个工作协程使用独立的租约持有者，租约过期后被同一实例的其他协程领取时也能识别
+	owner := fmt.Sprintf("%s/%d", q.owner, id)
 	q.logger.Debug("started worker", loggerPkg.String("worker", workerName))
 
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
 	for {
 		select {
 		case <-q.ctx.Done():
 			q.logger.Debug("shutting down worker", loggerPkg.String("worker", workerName))
 			return
-		case job, ok := <-q.jobQueue:
-			if !ok {
+		default:
+		}
+
+		record, err := q.backend.Lease(q.ctx, owner, leaseTTL)
+		if err != nil && q.ctx.Err() == nil {
+			q.logger.Error("failed to lease job", loggerPkg.String("worker", workerName), loggerPkg.String("error", err.Error()))
+		}
+		if record == nil {
+			select {
+			case <-q.ctx.Done():
+				q.logger.Debug("shutting down worker", loggerPkg.String("worker", workerName))
 				return
+			case <-q.wakeup:
+			case <-ticker.C:
 			}
+			continue
+		}
 
-			// 获取信号
-			q.workerPool <- struct{}{}
+		q.process(workerName, record)
+	}
+}
 
-			// 执行任务
-			start := time.Now()
-			err := job.Execute()
-			duration := time.Since(start)
+// process 执行已领取的任务并根据结果确认、重试或移入死信
+func (q *WorkQueue) process(workerName string, record *JobRecord) {
+	job, err := q.jobFor(record)
+	if err != nil {
+		q.logger.Error("failed to load job", loggerPkg.String("worker", workerName), loggerPkg.String("job", record.ID), loggerPkg.String("error", err.Error()))
+		record.Attempts = append(record.Attempts, JobAttempt{
+			Attempt:    len(record.Attempts) + 1,
+			StartedAt:  time.Now(),
+			FinishedAt: time.Now(),
+			Error:      err.Error(),
+		})
+		if buryErr := q.backend.Bury(context.Background(), record); buryErr != nil {
+			q.logger.Warn("failed to move job to dead letter store", loggerPkg.String("error", buryErr.Error()))
+		}
+		q.releaseJob(record.ID)
+		return
+	}
 
-			// 释放信号
-			<-q.workerPool
+	if cp, ok := job.(Checkpointable); ok {
+		cp.SetCheckpoint(func() error { return q.checkpoint(record, job) })
+	}
 
-			if err != nil {
-				q.logger.Error("job failed", loggerPkg.String("worker", workerName), loggerPkg.String("duration", duration.String()), loggerPkg.String("error", err.Error()))
-				// 删除失败任务的持久化文件，避免重复调用
-				if deleteErr := q.deleteJob(job); deleteErr != nil {
-					q.logger.Warn("failed to delete persisted job after failure", loggerPkg.String("error", deleteErr.Error()))
-				}
-			} else {
-				q.logger.Debug("job completed", loggerPkg.String("worker", workerName), loggerPkg.String("duration", duration.String()))
-				// 任务成功完成后删除持久化文件
-				if deleteErr := q.deleteJob(job); deleteErr != nil {
-					q.logger.Warn("failed to delete persisted job", loggerPkg.String("error", deleteErr.Error()))
-				}
-			}
+	// 获取信号
+	q.workerPool <- struct{}{}
+
+	// 执行任务
+	jobCtx, cancel, release := q.jobContext(record.ID)
+	stopHeartbeat := q.heartbeat(record, cancel)
+	q.markRunning(record)
+	notifyState(job, JobStateRunning, nil)
+	start := time.Now()
+	err = job.Execute(jobCtx)
+	duration := time.Since(start)
+	stopHeartbeat()
+	cause := context.Cause(jobCtx)
+	release()
+
+	// 释放信号
+	<-q.workerPool
+
+	// 任务已被取消，记录已从后端删除
+	if err != nil && stdErrors.Is(cause, ErrJobCancelled) {
+		q.logger.Info("job cancelled", loggerPkg.String("worker", workerName), loggerPkg.String("job", record.ID))
+		q.finishCancelled(record, job)
+		return
+	}
+
+	// 租约已转给其他执行者，任务记录与状态由其处理
+	if err != nil && stdErrors.Is(cause, ErrLeaseLost) {
+		q.logger.Warn("job lease lost, leaving it to the new owner", loggerPkg.String("worker", workerName), loggerPkg.String("job", record.ID))
+		return
+	}
+
+	// 队列关闭时中断，保留检查点等待下次启动恢复
+	if err != nil && stdErrors.Is(cause, ErrQueueShutdown) {
+		if data, marshalErr := json.Marshal(job.GetData()); marshalErr == nil {
+			record.Data = data
 		}
+		q.requeueInterrupted(record)
+		return
 	}
-}
 
-func (q *WorkQueue) AddJob(job Job) {
-	select {
-	case <-q.ctx.Done():
-		q.logger.Warn("attempting to add job to stopped queue", loggerPkg.String("queue", q.name))
+	// 记录执行后的任务数据，重试时从最新检查点继续
+	if data, marshalErr := json.Marshal(job.GetData()); marshalErr == nil {
+		record.Data = data
+	}
+
+	if err != nil {
+		state := q.handleFailure(record, start, err)
+		q.markFinished(record, state, duration)
+		notifyState(job, state, err)
+		if state == JobStateFailed {
+			q.forget(record.ID)
+			q.releaseJob(record.ID)
+		}
 		return
-	default:
-		// 持久化任务
-		if err := q.saveJob(job); err != nil {
+	}
+
+	q.logger.Debug("job completed", loggerPkg.String("worker", workerName), loggerPkg.String("duration", duration.String()))
+	// 任务成功完成后删除持久化记录
+	if ackErr := q.backend.Ack(context.Background(), record); ackErr != nil {
+		q.logger.Warn("failed to delete persisted job", loggerPkg.String("job", record.ID), loggerPkg.String("error", ackErr.Error()))
+	}
+	q.markFinished(record, JobStateSucceeded, duration)
+	notifyState(job, JobStateSucceeded, nil)
+	q.forget(record.ID)
+	q.releaseJob(record.ID)
+}
+
+// queuedCount 统计等待执行的任务数量
+func queuedCount(records []*JobRecord) int {
+	count := 0
+	for _, record := range records {
+		if record.State == JobStateQueued || record.State == JobStateRetrying {
+			count++
+		}
+	}
+	return count
+}
+
+// AddJob 添加任务，同ID任务未结束时不重复入队，返回已有任务状态。
+// 队列已满时在 ctx 与入队超时内等待空位，超时返回队列已满错误
+func (q *WorkQueue) AddJob(ctx context.Context, job Job) (*JobStatus, bool, error) {
+	return q.addJob(ctx, job, 0)
+}
+
+// addJob 添加任务，requeues 为任务从死信重新入队的次数，随记录持久化
+func (q *WorkQueue) addJob(ctx context.Context, job Job, requeues int) (*JobStatus, bool, error) {
+	if q.ctx.Err() != nil {
+		return nil, false, errors.NewInternalError("queue is stopped", nil)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, q.enqueueTimeout)
+	defer cancel()
+
+	var record *JobRecord
+	for {
+		records, err := q.backend.List(ctx)
+		if err != nil {
+			q.logger.Error("failed to list persisted jobs", loggerPkg.String("error", err.Error()))
+			return nil, false, errors.NewInternalError("failed to enqueue job", err)
+		}
+
+		// 同一任务未结束时直接复用，不受容量限制
+		if status, ok := q.attach(job.GetID(), records); ok {
+			return status, false, nil
+		}
+
+		record, err = newJobRecord(job)
+		if err != nil {
+			q.logger.Error("failed to marshal job", loggerPkg.String("error", err.Error()))
+			return nil, false, errors.NewInternalError("failed to marshal job", err)
+		}
+		record.Requeues = requeues
+		assignVirtualTime(record, records)
+
+		// 持久化任务，后端在同一锁或事务内检查容量并写入
+		err = q.backend.Enqueue(ctx, record, q.queueSize)
+		if err == nil {
+			break
+		}
+		if stdErrors.Is(err, ErrJobExists) {
+			if status, ok := q.GetJobStatus(job.GetID()); ok {
+				q.logger.Info("job already in flight, attaching", loggerPkg.String("job", job.GetID()), loggerPkg.String("state", string(status.State)))
+				return status, false, nil
+			}
+		}
+		if !stdErrors.Is(err, ErrQueueFull) {
 			q.logger.Error("failed to persist job", loggerPkg.String("error", err.Error()))
+			return nil, false, errors.NewInternalError("failed to enqueue job", err)
+		}
+
+		// 排队任务达到上限时等待空位
+		select {
+		case <-q.ctx.Done():
+			return nil, false, errors.NewInternalError("queue is stopped", nil)
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return nil, false, errors.NewInternalError("enqueue cancelled", ctx.Err())
+			}
+			q.logger.Warn("queue is full, rejecting job", loggerPkg.String("queue", q.name), loggerPkg.String("job", job.GetID()))
+			return nil, false, errors.NewQueueFullError(q.name, q.EstimateWait(queuedCount(records)))
+		case <-time.After(pollInterval):
+		}
+	}
+	q.remember(job)
+	notifyState(job, JobStateQueued, nil)
+	q.notify()
+
+	if status, ok := q.GetJobStatus(job.GetID()); ok {
+		return status, true, nil
+	}
+	return &JobStatus{
+		ID:         record.ID,
+		Type:       record.Type,
+		State:      record.State,
+		Labels:     record.Labels,
+		EnqueuedAt: record.EnqueuedAt,
+	}, true, nil
+}
+
+// attach 检查同ID任务是否仍在队列中
+func (q *WorkQueue) attach(id string, records []*JobRecord) (*JobStatus, bool) {
+	for _, record := range records {
+		if record.ID != id {
+			continue
 		}
-		q.jobQueue <- job
+		status, ok := q.GetJobStatus(id)
+		if !ok {
+			return nil, false
+		}
+		q.logger.Info("job already in flight, attaching", loggerPkg.String("job", id), loggerPkg.String("state", string(status.State)))
+		return status, true
 	}
+	return nil, false
 }
 
-func (q *WorkQueue) Stop() {
+// Stop 停止领取新任务并等待执行中的任务结束，ctx 结束后中断剩余任务，
+// 被中断的任务保留在队列中，下次启动时从检查点恢复
+func (q *WorkQueue) Stop(ctx context.Context) {
 	q.logger.Info("stopping queue", loggerPkg.String("queue", q.name))
 	q.cancelFunc()
-	close(q.jobQueue)
-	q.wg.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		q.logger.Warn("grace period expired, interrupting running jobs", loggerPkg.String("queue", q.name))
+		q.interruptRunning()
+		<-done
+	}
+
 	close(q.shutdownChan)
 	q.logger.Info("queue stopped", loggerPkg.String("queue", q.name))
 }
 
+// StopQueues 并行停止所有队列
+func StopQueues(ctx context.Context) {
+	queueMutex.Lock()
+	all := make([]*WorkQueue, 0, len(queues))
+	for _, q := range queues {
+		all = append(all, q)
+	}
+	queueMutex.Unlock()
+
+	var wg sync.WaitGroup
+	for _, q := range all {
+		wg.Add(1)
+		go func(q *WorkQueue) {
+			defer wg.Done()
+			q.Stop(ctx)
+		}(q)
+	}
+	wg.Wait()
+}
+
 func GetQueue(name string) *WorkQueue {
 	queueMutex.Lock()
 	defer queueMutex.Unlock()
 	return queues[name]
 }
 
-func InitQueues(cfg *config.Config, logger loggerPkg.Logger) {
+// newBackend 按配置创建队列后端
+func newBackend(cfg *config.Config, name string, logger loggerPkg.Logger) (QueueBackend, error) {
+	switch cfg.QueueBackend {
+	case "", "file":
+		return NewFileBackend(filepath.Join("data", "queues", name), logger)
+	case "mysql":
+		db := database.GetDB()
+		if db == nil {
+			return nil, fmt.Errorf("database not initialized")
+		}
+		return NewSQLBackend(db, name, logger), nil
+	default:
+		return nil, fmt.Errorf("unknown queue backend: %s", cfg.QueueBackend)
+	}
+}
+
+func InitQueues(cfg *config.Config, logger loggerPkg.Logger) error {
 	// Summary Service
-	summaryQueue := NewWorkQueue("SummaryServiceQueue", cfg.SummaryWorkerCount, cfg.SummaryQueueSize, logger)
+	summaryBackend, err := newBackend(cfg, "SummaryServiceQueue", logger)
+	if err != nil {
+		return err
+	}
+	summaryQueue := NewWorkQueue("SummaryServiceQueue", cfg.SummaryWorkerCount, cfg.SummaryQueueSize, summaryBackend, logger)
+	summaryQueue.RegisterRetryPolicy("summary", RetryPolicy{
+		MaxAttempts: cfg.SummaryMaxAttempts,
+		BaseDelay:   time.Duration(cfg.SummaryRetryDelay) * time.Second,
+		MaxDelay:    time.Duration(cfg.SummaryRetryMaxDelay) * time.Second,
+		Jitter:      0.2,
+	})
+	summaryQueue.SetEnqueueTimeout(time.Duration(cfg.SummaryEnqueueTimeout) * time.Second)
 	summaryQueue.Start(cfg)
+	return nil
 }
@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	stdErrors "errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	loggerPkg "iwut-smartclass-backend/internal/infrastructure/logger"
+)
+
+// RetryPolicy 任务重试策略
+type RetryPolicy struct {
+	MaxAttempts int           // 最大尝试次数（包含首次执行）
+	BaseDelay   time.Duration // 首次重试前的等待时间
+	MaxDelay    time.Duration // 重试等待时间上限
+	Jitter      float64       // 随机抖动比例，取值 0~1
+}
+
+// JobAttempt 单次执行记录
+type JobAttempt struct {
+	Attempt    int       `json:"attempt"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Error      string    `json:"error"`
+}
+
+// 未注册策略的任务类型只执行一次，失败后直接进入死信目录
+var defaultRetryPolicy = RetryPolicy{MaxAttempts: 1}
+
+var globalRetryPolicies = make(map[string]RetryPolicy)
+
+// Backoff 计算第 attempt 次失败后的等待时间（指数退避 + 抖动）
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (rand.Float64()*2 - 1)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return time.Duration(delay)
+}
+
+// ShouldRetry 检查已执行 attempts 次后是否还能重试
+func (p RetryPolicy) ShouldRetry(attempts int) bool {
+	return attempts < p.MaxAttempts
+}
+
+func RegisterGlobalRetryPolicy(jobType string, policy RetryPolicy) {
+	globalRetryPolicies[jobType] = policy
+}
+
+func (q *WorkQueue) RegisterRetryPolicy(jobType string, policy RetryPolicy) {
+	q.retryPolicies[jobType] = policy
+}
+
+func (q *WorkQueue) retryPolicy(jobType string) RetryPolicy {
+	if policy, ok := q.retryPolicies[jobType]; ok {
+		return policy
+	}
+	return defaultRetryPolicy
+}
+
+// handleFailure 记录失败并按重试策略归还任务，重试次数耗尽时移入死信，返回任务的新状态
+func (q *WorkQueue) handleFailure(record *JobRecord, start time.Time, err error) JobState {
+	record.Attempts = append(record.Attempts, JobAttempt{
+		Attempt:    len(record.Attempts) + 1,
+		StartedAt:  start,
+		FinishedAt: time.Now(),
+		Error:      err.Error(),
+	})
+
+	policy := q.retryPolicy(record.Type)
+	if policy.ShouldRetry(len(record.Attempts)) {
+		delay := policy.Backoff(len(record.Attempts))
+		record.AvailableAt = time.Now().Add(delay)
+		q.logger.Warn("job failed, scheduling retry", loggerPkg.String("job", record.ID), loggerPkg.String("attempt", fmt.Sprintf("%d/%d", len(record.Attempts), policy.MaxAttempts)), loggerPkg.String("delay", delay.String()), loggerPkg.String("error", err.Error()))
+		if nackErr := q.backend.Nack(context.Background(), record, record.AvailableAt); nackErr != nil {
+			q.logger.Error("failed to schedule retry", loggerPkg.String("job", record.ID), loggerPkg.String("error", nackErr.Error()))
+		}
+		return JobStateRetrying
+	}
+
+	q.logger.Error("job failed", loggerPkg.String("job", record.ID), loggerPkg.String("attempts", fmt.Sprintf("%d", len(record.Attempts))), loggerPkg.String("error", err.Error()))
+	// 重试次数耗尽，移入死信等待人工处理
+	if buryErr := q.backend.Bury(context.Background(), record); buryErr != nil {
+		q.logger.Warn("failed to move job to dead letter store", loggerPkg.String("job", record.ID), loggerPkg.String("error", buryErr.Error()))
+		// 租约已转给其他执行者时由其处理，不能删除
+		if stdErrors.Is(buryErr, ErrLeaseLost) {
+			return JobStateFailed
+		}
+		if ackErr := q.backend.Ack(context.Background(), record); ackErr != nil {
+			q.logger.Warn("failed to delete persisted job after failure", loggerPkg.String("error", ackErr.Error()))
+		}
+	}
+	return JobStateFailed
+}
@@ -0,0 +1,429 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	_struct "iwut-smartclass-backend/internal/database/struct"
+	loggerPkg "iwut-smartclass-backend/internal/infrastructure/logger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 每次领取时检查的候选任务数量
+const sqlLeaseCandidates = 10
+
+// SQLBackend 基于 MySQL 的队列后端，通过行租约支持多个副本共享同一队列
+type SQLBackend struct {
+	db     *gorm.DB
+	queue  string
+	logger loggerPkg.Logger
+}
+
+// NewSQLBackend 创建 MySQL 队列后端
+func NewSQLBackend(db *gorm.DB, queue string, logger loggerPkg.Logger) *SQLBackend {
+	return &SQLBackend{
+		db:     db,
+		queue:  queue,
+		logger: logger,
+	}
+}
+
+func toMillis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func fromMillis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+func marshalString(v interface{}) (string, error) {
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes), nil
+}
+
+func (b *SQLBackend) toRow(record *JobRecord) (*_struct.QueueJob, error) {
+	labels, err := marshalString(record.Labels)
+	if err != nil {
+		return nil, err
+	}
+	attempts, err := marshalString(record.Attempts)
+	if err != nil {
+		return nil, err
+	}
+	return &_struct.QueueJob{
+		Queue:       b.queue,
+		ID:          record.ID,
+		Type:        record.Type,
+		Data:        string(record.Data),
+		Labels:      labels,
+		Priority:    record.Priority,
+		Owner:       record.Owner,
+		Attempts:    attempts,
+		State:       string(record.State),
+		EnqueuedAt:  toMillis(record.EnqueuedAt),
+		VirtualAt:   toMillis(record.VirtualAt),
+		AvailableAt: toMillis(record.AvailableAt),
+		LeaseOwner:  record.LeaseOwner,
+		LeaseToken:  record.LeaseToken,
+		LeaseUntil:  toMillis(record.LeaseUntil),
+		Requeues:    record.Requeues,
+	}, nil
+}
+
+func fromRow(row *_struct.QueueJob) *JobRecord {
+	record := &JobRecord{
+		ID:          row.ID,
+		Type:        row.Type,
+		Data:        json.RawMessage(row.Data),
+		Priority:    row.Priority,
+		Owner:       row.Owner,
+		State:       JobState(row.State),
+		EnqueuedAt:  fromMillis(row.EnqueuedAt),
+		VirtualAt:   fromMillis(row.VirtualAt),
+		AvailableAt: fromMillis(row.AvailableAt),
+		LeaseOwner:  row.LeaseOwner,
+		LeaseToken:  row.LeaseToken,
+		LeaseUntil:  fromMillis(row.LeaseUntil),
+		Requeues:    row.Requeues,
+	}
+	_ = json.Unmarshal([]byte(row.Labels), &record.Labels)
+	_ = json.Unmarshal([]byte(row.Attempts), &record.Attempts)
+	return record
+}
+
+func fromDeadRow(row *_struct.QueueDeadJob) *JobRecord {
+	record := &JobRecord{
+		ID:       row.ID,
+		Type:     row.Type,
+		Data:     json.RawMessage(row.Data),
+		State:    JobStateFailed,
+		DeadAt:   fromMillis(row.DeadAt),
+		Requeues: row.Requeues,
+	}
+	_ = json.Unmarshal([]byte(row.Labels), &record.Labels)
+	_ = json.Unmarshal([]byte(row.Attempts), &record.Attempts)
+	return record
+}
+
+// leasableScope 可领取条件：排队或等待重试且已到时间，或运行中但租约已过期
+func leasableScope(now int64) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(
+			"((state IN ? AND available_at <= ?) OR (state = ? AND lease_until < ?))",
+			[]string{string(JobStateQueued), string(JobStateRetrying)}, now,
+			string(JobStateRunning), now,
+		)
+	}
+}
+
+func (b *SQLBackend) Enqueue(ctx context.Context, record *JobRecord, capacity int) error {
+	row, err := b.toRow(record)
+	if err != nil {
+		return err
+	}
+	if capacity <= 0 {
+		return b.insert(b.db.WithContext(ctx), row)
+	}
+
+	// 先在事务外确保锁行存在，避免并发事务同时持有共享锁后升级为排他锁而死锁
+	if err := b.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&_struct.QueueLock{Queue: b.queue}).Error; err != nil {
+		return err
+	}
+	return b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 锁定队列，其他副本的入队在此等待，容量检查与写入之间不会插入新任务
+		var lock _struct.QueueLock
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("queue = ?", b.queue).First(&lock).Error; err != nil {
+			return err
+		}
+
+		var exists int64
+		if err := tx.Model(&_struct.QueueJob{}).Where("queue = ? AND id = ?", b.queue, record.ID).Count(&exists).Error; err != nil {
+			return err
+		}
+		if exists > 0 {
+			return ErrJobExists
+		}
+
+		var queued int64
+		err := tx.Model(&_struct.QueueJob{}).
+			Where("queue = ? AND state IN ?", b.queue, []string{string(JobStateQueued), string(JobStateRetrying)}).
+			Count(&queued).Error
+		if err != nil {
+			return err
+		}
+		if queued >= int64(capacity) {
+			return ErrQueueFull
+		}
+		return b.insert(tx, row)
+	})
+}
+
+// insert 写入任务行，同ID任务已存在时返回 ErrJobExists
+func (b *SQLBackend) insert(db *gorm.DB, row *_struct.QueueJob) error {
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(row)
+	if result.Error != nil {
+		b.logger.Error("failed to enqueue job", loggerPkg.String("error", result.Error.Error()))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrJobExists
+	}
+	return nil
+}
+
+func (b *SQLBackend) Lease(ctx context.Context, owner string, ttl time.Duration) (*JobRecord, error) {
+	now := time.Now()
+	var candidates []_struct.QueueJob
+	err := b.db.WithContext(ctx).
+		Where("queue = ?", b.queue).
+		Scopes(leasableScope(now.UnixMilli())).
+		Order("priority DESC, virtual_at ASC, enqueued_at ASC").
+		Limit(sqlLeaseCandidates).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, candidate := range candidates {
+		// 乐观加锁：只有条件仍满足时才能更新成功，避免多个副本领取同一任务
+		token := newLeaseToken()
+		result := b.db.WithContext(ctx).Model(&_struct.QueueJob{}).
+			Where("queue = ? AND id = ?", b.queue, candidate.ID).
+			Scopes(leasableScope(now.UnixMilli())).
+			Updates(map[string]interface{}{
+				"state":       string(JobStateRunning),
+				"lease_owner": owner,
+				"lease_token": token,
+				"lease_until": now.Add(ttl).UnixMilli(),
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			candidate.State = string(JobStateRunning)
+			candidate.LeaseOwner = owner
+			candidate.LeaseToken = token
+			candidate.LeaseUntil = now.Add(ttl).UnixMilli()
+			return fromRow(&candidate), nil
+		}
+	}
+	return nil, nil
+}
+
+func (b *SQLBackend) Extend(ctx context.Context, record *JobRecord, ttl time.Duration) error {
+	result := b.db.WithContext(ctx).Model(&_struct.QueueJob{}).
+		Scopes(b.leasedScope(record)).
+		Update("lease_until", time.Now().Add(ttl).UnixMilli())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	var count int64
+	if err := b.db.WithContext(ctx).Model(&_struct.QueueJob{}).Where("queue = ? AND id = ?", b.queue, record.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrJobNotFound
+	}
+	return ErrLeaseLost
+}
+
+// leasedScope 以租约为条件的写入：只匹配仍由 record 的租约持有者与租约令牌持有的任务
+func (b *SQLBackend) leasedScope(record *JobRecord) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("queue = ? AND id = ? AND lease_owner = ? AND lease_token = ?", b.queue, record.ID, record.LeaseOwner, record.LeaseToken)
+	}
+}
+
+func (b *SQLBackend) Update(ctx context.Context, record *JobRecord) error {
+	attempts, err := marshalString(record.Attempts)
+	if err != nil {
+		return err
+	}
+	result := b.db.WithContext(ctx).Model(&_struct.QueueJob{}).
+		Scopes(b.leasedScope(record)).
+		Updates(map[string]interface{}{
+			"data":     string(record.Data),
+			"attempts": attempts,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	// MySQL 默认只统计内容有变化的行，数据未变时再确认租约是否仍然有效
+	var count int64
+	if err := b.db.WithContext(ctx).Model(&_struct.QueueJob{}).Scopes(b.leasedScope(record)).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (b *SQLBackend) Ack(ctx context.Context, record *JobRecord) error {
+	result := b.db.WithContext(ctx).
+		Scopes(b.leasedScope(record)).
+		Delete(&_struct.QueueJob{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (b *SQLBackend) Remove(ctx context.Context, id string) (*JobRecord, error) {
+	var removed *JobRecord
+	err := b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rows []_struct.QueueJob
+		if err := tx.Where("queue = ? AND id = ?", b.queue, id).Limit(1).Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return ErrJobNotFound
+		}
+		removed = fromRow(&rows[0])
+		return tx.Where("queue = ? AND id = ?", b.queue, id).Delete(&_struct.QueueJob{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+func (b *SQLBackend) Nack(ctx context.Context, record *JobRecord, availableAt time.Time) error {
+	attempts, err := marshalString(record.Attempts)
+	if err != nil {
+		return err
+	}
+	result := b.db.WithContext(ctx).Model(&_struct.QueueJob{}).
+		Scopes(b.leasedScope(record)).
+		Updates(map[string]interface{}{
+			"data":         string(record.Data),
+			"attempts":     attempts,
+			"state":        string(JobStateRetrying),
+			"available_at": availableAt.UnixMilli(),
+			"lease_owner":  "",
+			"lease_token":  "",
+			"lease_until":  0,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (b *SQLBackend) List(ctx context.Context) ([]*JobRecord, error) {
+	var rows []_struct.QueueJob
+	if err := b.db.WithContext(ctx).Where("queue = ?", b.queue).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	records := make([]*JobRecord, 0, len(rows))
+	for i := range rows {
+		records = append(records, fromRow(&rows[i]))
+	}
+	return records, nil
+}
+
+func (b *SQLBackend) Bury(ctx context.Context, record *JobRecord) error {
+	labels, err := marshalString(record.Labels)
+	if err != nil {
+		return err
+	}
+	attempts, err := marshalString(record.Attempts)
+	if err != nil {
+		return err
+	}
+	dead := &_struct.QueueDeadJob{
+		Queue:    b.queue,
+		ID:       record.ID,
+		Type:     record.Type,
+		Data:     string(record.Data),
+		Labels:   labels,
+		Attempts: attempts,
+		DeadAt:   time.Now().UnixMilli(),
+		Requeues: record.Requeues,
+	}
+
+	return b.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Scopes(b.leasedScope(record)).Delete(&_struct.QueueJob{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrLeaseLost
+		}
+		if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(dead).Error; err != nil {
+			return fmt.Errorf("failed to save dead job: %w", err)
+		}
+		return nil
+	})
+}
+
+func (b *SQLBackend) ListDead(ctx context.Context) ([]*JobRecord, error) {
+	var rows []_struct.QueueDeadJob
+	err := b.db.WithContext(ctx).
+		Where("queue = ?", b.queue).
+		Order("dead_at DESC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*JobRecord, 0, len(rows))
+	for i := range rows {
+		records = append(records, fromDeadRow(&rows[i]))
+	}
+	return records, nil
+}
+
+func (b *SQLBackend) GetDead(ctx context.Context, id string) (*JobRecord, error) {
+	var rows []_struct.QueueDeadJob
+	err := b.db.WithContext(ctx).
+		Where("queue = ? AND id = ?", b.queue, id).
+		Limit(1).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrDeadJobNotFound
+	}
+	return fromDeadRow(&rows[0]), nil
+}
+
+func (b *SQLBackend) RemoveDead(ctx context.Context, id string) error {
+	result := b.db.WithContext(ctx).
+		Where("queue = ? AND id = ?", b.queue, id).
+		Delete(&_struct.QueueDeadJob{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDeadJobNotFound
+	}
+	return nil
+}